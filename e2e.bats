@@ -10,7 +10,7 @@
 
   # request is rejected
   [ "$status" -eq 0 ]
-  [[ "$output" =~ "container 'test-container': liveness probe period (5s) is less than minimum required (10s)" ]]
+  [[ "$output" =~ "container 'test-container': liveness probe periodSeconds (5s) is less than minimum required (10s)" ]]
 }
 
 @test "reject deployment with invalid probe timeout" {
@@ -23,7 +23,7 @@
 
   # request is rejected
   [ "$status" -eq 0 ]
-  [[ "$output" =~ "container 'test-container': liveness probe timeout (10s) exceeds maximum allowed (5s)" ]]
+  [[ "$output" =~ "container 'test-container': liveness probe timeoutSeconds (10s) exceeds maximum allowed (5s)" ]]
 }
 
 @test "accept deployment with valid probe configurations" {
@@ -90,3 +90,45 @@
   [ "$status" -eq 0 ]
   [[ "$output" =~ "deployment validation succeeded" ]]
 }
+
+@test "reject pod with missing readiness probe" {
+  run kwctl run annotated-policy.wasm \
+    -r test_data/pod.json \
+    --settings-json '{}'
+
+  # this prints the output when one the checks below fails
+  echo "output = ${output}"
+
+  # request is rejected
+  [ "$status" -eq 0 ]
+  [[ "$output" =~ "container 'pause': missing readiness probe" ]]
+}
+
+@test "accept every supported pod controller kind" {
+  for fixture in replicaset statefulset daemonset job cronjob replicationcontroller; do
+    run kwctl run annotated-policy.wasm \
+      -r "test_data/${fixture}.json" \
+      --settings-json '{"liveness_probe": {"required": true}, "readiness_probe": {"required": true}}'
+
+    # this prints the output when one the checks below fails
+    echo "fixture = ${fixture}, output = ${output}"
+
+    # request is accepted
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "deployment validation succeeded" ]]
+  done
+}
+
+@test "inject missing readiness probe in mutating mode" {
+  run kwctl run annotated-policy-mutating.wasm \
+    -r test_data/deployment-missing-probes.json \
+    --settings-json '{"readiness_probe": {"required": true}, "mutation": {"enabled": true}}'
+
+  # this prints the output when one the checks below fails
+  echo "output = ${output}"
+
+  # request is accepted and mutated
+  [ "$status" -eq 0 ]
+  [[ "$output" =~ "probe injected" ]]
+  [[ "$output" =~ "JSONPatch" ]]
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Annotations opting a workload out of some probe checks。
+const (
+	exemptAnnotation  = "probes-check.kubewarden.io/exempt"
+	reasonAnnotation  = "probes-check.kubewarden.io/reason"
+	expiresAnnotation = "probes-check.kubewarden.io/expires"
+)
+
+// exemptAllProbes is the exempt annotation value covering every probe type。
+const exemptAllProbes = "all"
+
+// expiresDateLayout is the layout of the expires annotation, the exemption is valid until the end
+// of that day (UTC)。
+const expiresDateLayout = "2006-01-02"
+
+// hoursPerDay is the number of hours in a day, used to round the current time down to the day。
+const hoursPerDay = 24
+
+// now returns the current time, used to check exemption expiry。
+//
+//nolint:gochecknoglobals // Replaced by the tests to control the current time.
+var now = time.Now
+
+// probeExemption represents the probe checks a workload opted out of through its annotations。
+type probeExemption struct {
+	probes  []string
+	reason  string
+	expires string
+}
+
+// annotationExemption returns the probe types exempted by the annotations of the workload or, when
+// the workload has none, of its pod template, and whether the workload carries an exemption。An
+// exemption must carry a reason and an expiry date that has not passed yet and is at most maxDays
+// away, otherwise an error describing the problem is returned。
+func annotationExemption(kind string, objectJSON []byte, maxDays int) (probeExemption, bool, error) {
+	specPath, err := podSpecPath(kind)
+	if err != nil {
+		return probeExemption{}, false, err
+	}
+
+	annotations := gjson.GetBytes(objectJSON, "metadata.annotations").Map()
+	if _, found := annotations[exemptAnnotation]; !found {
+		annotations = gjson.GetBytes(objectJSON, podTemplateMetadataPath(specPath)+".annotations").Map()
+	}
+
+	exempt, found := annotations[exemptAnnotation]
+	if !found {
+		return probeExemption{}, false, nil
+	}
+
+	probes := []string{}
+	for _, probe := range strings.Split(exempt.String(), ",") {
+		probe = strings.TrimSpace(probe)
+		switch {
+		case probe == exemptAllProbes:
+			probes = append(probes, probeTypes...)
+		case containsString(probeTypes, probe):
+			probes = append(probes, probe)
+		default:
+			return probeExemption{}, false, fmt.Errorf("annotation %s: unknown probe type '%s', must be one of: %s, %s",
+				exemptAnnotation, probe, strings.Join(probeTypes, ", "), exemptAllProbes)
+		}
+	}
+
+	reason := strings.TrimSpace(annotations[reasonAnnotation].String())
+	if reason == "" {
+		return probeExemption{}, false, fmt.Errorf("annotation %s is required to exempt probes", reasonAnnotation)
+	}
+
+	expires := annotations[expiresAnnotation].String()
+	if expires == "" {
+		return probeExemption{}, false, fmt.Errorf("annotation %s is required to exempt probes", expiresAnnotation)
+	}
+	expiresDate, err := time.Parse(expiresDateLayout, expires)
+	if err != nil {
+		return probeExemption{}, false, fmt.Errorf("annotation %s: invalid date '%s', expected format YYYY-MM-DD",
+			expiresAnnotation, expires)
+	}
+	if !now().Before(expiresDate.AddDate(0, 0, 1)) {
+		return probeExemption{}, false, errors.New("probe exemption expired on " + expires)
+	}
+	if today := now().UTC().Truncate(hoursPerDay * time.Hour); expiresDate.After(today.AddDate(0, 0, maxDays)) {
+		return probeExemption{}, false, fmt.Errorf("probe exemption expires on %s, more than %d days from now",
+			expires, maxDays)
+	}
+
+	return probeExemption{probes: probes, reason: reason, expires: expires}, true, nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	kubewarden_protocol "github.com/kubewarden/policy-sdk-go/protocol"
+)
+
+func TestValidateExemptionAnnotations(t *testing.T) {
+	now = func() time.Time { return time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = time.Now })
+
+	settings := `{"liveness_probe": {"required": true}, "readiness_probe": {"required": true},
+		"allow_exemption_annotations": true, "max_exemption_days": 365}`
+	workloadAnnotations := `{
+		"probes-check.kubewarden.io/exempt": "liveness",
+		"probes-check.kubewarden.io/reason": "legacy image without health endpoint",
+		"probes-check.kubewarden.io/expires": "2026-12-31"
+	}`
+
+	tests := []struct {
+		name                 string
+		settings             string
+		annotations          string
+		templateAnnotations  string
+		shouldAllow          bool
+		expectedMessageStart string
+	}{
+		{
+			name:        "workload exemption",
+			settings:    settings,
+			annotations: workloadAnnotations,
+			shouldAllow: true,
+		},
+		{
+			name:                "pod template exemption",
+			settings:            settings,
+			templateAnnotations: workloadAnnotations,
+			shouldAllow:         true,
+		},
+		{
+			name: "exemption of every probe",
+			settings: `{"liveness_probe": {"required": true}, "readiness_probe": {"max_period_seconds": 1},
+				"allow_exemption_annotations": true}`,
+			annotations: `{
+				"probes-check.kubewarden.io/exempt": "all",
+				"probes-check.kubewarden.io/reason": "batch worker",
+				"probes-check.kubewarden.io/expires": "2026-06-15"
+			}`,
+			shouldAllow: true,
+		},
+		{
+			name: "other probes are still checked",
+			settings: `{"liveness_probe": {"required": true}, "readiness_probe": {"max_period_seconds": 1},
+				"allow_exemption_annotations": true, "max_exemption_days": 365}`,
+			annotations:          workloadAnnotations,
+			shouldAllow:          false,
+			expectedMessageStart: "container 'app': readiness probe periodSeconds (10s) exceeds maximum allowed (1s)",
+		},
+		{
+			name:     "missing reason",
+			settings: settings,
+			annotations: `{
+				"probes-check.kubewarden.io/exempt": "liveness",
+				"probes-check.kubewarden.io/expires": "2026-12-31"
+			}`,
+			shouldAllow:          false,
+			expectedMessageStart: "invalid probe exemption: annotation probes-check.kubewarden.io/reason is required",
+		},
+		{
+			name:     "missing expiry",
+			settings: settings,
+			annotations: `{
+				"probes-check.kubewarden.io/exempt": "liveness",
+				"probes-check.kubewarden.io/reason": "legacy"
+			}`,
+			shouldAllow:          false,
+			expectedMessageStart: "invalid probe exemption: annotation probes-check.kubewarden.io/expires is required",
+		},
+		{
+			name:     "malformed expiry",
+			settings: settings,
+			annotations: `{
+				"probes-check.kubewarden.io/exempt": "liveness",
+				"probes-check.kubewarden.io/reason": "legacy",
+				"probes-check.kubewarden.io/expires": "31/12/2026"
+			}`,
+			shouldAllow: false,
+			expectedMessageStart: "invalid probe exemption: annotation probes-check.kubewarden.io/expires: " +
+				"invalid date '31/12/2026'",
+		},
+		{
+			name:     "expired exemption",
+			settings: settings,
+			annotations: `{
+				"probes-check.kubewarden.io/exempt": "liveness",
+				"probes-check.kubewarden.io/reason": "legacy",
+				"probes-check.kubewarden.io/expires": "2026-06-14"
+			}`,
+			shouldAllow:          false,
+			expectedMessageStart: "invalid probe exemption: probe exemption expired on 2026-06-14",
+		},
+		{
+			name:     "unknown probe type",
+			settings: settings,
+			annotations: `{
+				"probes-check.kubewarden.io/exempt": "liveness,health",
+				"probes-check.kubewarden.io/reason": "legacy",
+				"probes-check.kubewarden.io/expires": "2026-12-31"
+			}`,
+			shouldAllow: false,
+			expectedMessageStart: "invalid probe exemption: annotation probes-check.kubewarden.io/exempt: " +
+				"unknown probe type 'health'",
+		},
+		{
+			name:     "exemption at the maximum duration",
+			settings: `{"liveness_probe": {"required": true}, "allow_exemption_annotations": true}`,
+			annotations: `{
+				"probes-check.kubewarden.io/exempt": "liveness",
+				"probes-check.kubewarden.io/reason": "legacy",
+				"probes-check.kubewarden.io/expires": "2026-09-13"
+			}`,
+			shouldAllow: true,
+		},
+		{
+			name:        "exemption beyond the maximum duration",
+			settings:    `{"liveness_probe": {"required": true}, "allow_exemption_annotations": true}`,
+			annotations: workloadAnnotations,
+			shouldAllow: false,
+			expectedMessageStart: "invalid probe exemption: probe exemption expires on 2026-12-31, " +
+				"more than 90 days from now",
+		},
+		{
+			name:                 "exemptions disabled by default",
+			settings:             `{"liveness_probe": {"required": true}}`,
+			annotations:          workloadAnnotations,
+			shouldAllow:          false,
+			expectedMessageStart: "container 'app': missing liveness probe",
+		},
+		{
+			name:                 "exemptions disabled",
+			settings:             `{"liveness_probe": {"required": true}, "allow_exemption_annotations": false}`,
+			annotations:          workloadAnnotations,
+			shouldAllow:          false,
+			expectedMessageStart: "container 'app': missing liveness probe",
+		},
+		{
+			name:        "malformed exemption ignored when disabled",
+			settings:    `{"allow_exemption_annotations": false}`,
+			annotations: `{"probes-check.kubewarden.io/exempt": "liveness"}`,
+			shouldAllow: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			metadata := `{"name": "app"}`
+			if test.annotations != "" {
+				metadata = `{"name": "app", "annotations": ` + test.annotations + `}`
+			}
+			templateMetadata := `{}`
+			if test.templateAnnotations != "" {
+				templateMetadata = `{"annotations": ` + test.templateAnnotations + `}`
+			}
+			deployment := `{
+				"apiVersion": "apps/v1",
+				"kind": "Deployment",
+				"metadata": ` + metadata + `,
+				"spec": {"template": {"metadata": ` + templateMetadata + `, "spec": {"containers": [
+					{"name": "app", "readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}}
+				]}}}
+			}`
+
+			request := kubewarden_protocol.ValidationRequest{
+				Request: kubewarden_protocol.KubernetesAdmissionRequest{
+					Operation: "CREATE",
+					Object:    json.RawMessage(deployment),
+				},
+				Settings: json.RawMessage(test.settings),
+			}
+
+			response := validateRequest(t, request)
+			if response.Accepted != test.shouldAllow {
+				t.Fatalf("Expected validation to return %v, got %v. Message: %v",
+					test.shouldAllow, response.Accepted, response.Message)
+			}
+			if !test.shouldAllow && !strings.HasPrefix(*response.Message, test.expectedMessageStart) {
+				t.Errorf("Expected message to start with %q, got %q", test.expectedMessageStart, *response.Message)
+			}
+		})
+	}
+}
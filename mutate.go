@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	corev1 "github.com/kubewarden/k8s-objects/api/core/v1"
+	kubewarden "github.com/kubewarden/policy-sdk-go"
+	kubewarden_protocol "github.com/kubewarden/policy-sdk-go/protocol"
+	"github.com/tidwall/gjson"
+)
+
+// Placeholders of the probe templates。
+const (
+	portPlaceholder      = "{{port}}"
+	containerPlaceholder = "{{container}}"
+)
+
+// defaultProbeTemplate is the probe injected when the settings define no template: a tcpSocket
+// probe on the first port declared by the container。
+const defaultProbeTemplate = `{"tcpSocket": {"port": "{{port}}"}}`
+
+// template returns the template of the given probe type。
+func (c MutationConfig) template(probeType string) string {
+	template := map[string]json.RawMessage{
+		"liveness":  c.LivenessProbe,
+		"readiness": c.ReadinessProbe,
+		"startup":   c.StartupProbe,
+	}[probeType]
+	if len(template) == 0 {
+		return defaultProbeTemplate
+	}
+	return string(template)
+}
+
+// renderProbe returns the probe of the given type for the container, built from its template。A
+// quoted port placeholder is replaced by the port number, so the probe targets a numeric port。A
+// port lower than or equal to zero means that the container declares no port。
+func (c MutationConfig) renderProbe(probeType, containerName string, port int32) (*corev1.Probe, error) {
+	template := c.template(probeType)
+	if strings.Contains(template, portPlaceholder) && port <= 0 {
+		return nil, errors.New("the container declares no port")
+	}
+
+	portValue := strconv.FormatInt(int64(port), 10)
+	rendered := strings.ReplaceAll(template, `"`+portPlaceholder+`"`, portValue)
+	rendered = strings.ReplaceAll(rendered, portPlaceholder, portValue)
+	rendered = strings.ReplaceAll(rendered, containerPlaceholder, containerName)
+
+	if !gjson.Valid(rendered) {
+		return nil, errors.New("invalid JSON")
+	}
+	if _, err := probeHandler(gjson.Parse(rendered)); err != nil {
+		return nil, err
+	}
+	probe := &corev1.Probe{}
+	if err := json.Unmarshal([]byte(rendered), probe); err != nil {
+		return nil, err
+	}
+	return probe, nil
+}
+
+// firstContainerPort returns the first port declared by the container, or zero。
+func firstContainerPort(container *corev1.Container) int32 {
+	for _, port := range container.Ports {
+		if port != nil && port.ContainerPort != nil {
+			return *port.ContainerPort
+		}
+	}
+	return 0
+}
+
+// mutateMissingProbes injects the missing required probes recorded in found into the pod spec of
+// the request。It returns the mutation response when the mutated object satisfies the policy, and
+// whether it did。
+func mutateMissingProbes(validationRequest kubewarden_protocol.ValidationRequest, kind string, settings Settings,
+	exempted []string, found *violations) ([]byte, bool) {
+	validationRequest.Request.Kind.Kind = kind
+	podSpec, err := kubewarden.ExtractPodSpecFromObject(validationRequest)
+	if err != nil {
+		logger.WarnWith("cannot extract pod spec, probes not injected").
+			Err("error", err).
+			Write()
+		return nil, false
+	}
+
+	missing := found.missingProbes()
+	for _, container := range podSpec.Containers {
+		if container == nil || container.Name == nil {
+			continue
+		}
+		for _, probeType := range missing[*container.Name] {
+			probe, renderErr := settings.Mutation.renderProbe(probeType, *container.Name, firstContainerPort(container))
+			if renderErr != nil {
+				logger.InfoWith("cannot inject probe").
+					String("container", *container.Name).
+					String("probe", probeType).
+					Err("error", renderErr).
+					Write()
+				return nil, false
+			}
+			switch probeType {
+			case "liveness":
+				container.LivenessProbe = probe
+			case "readiness":
+				container.ReadinessProbe = probe
+			case "startup":
+				container.StartupProbe = probe
+			}
+			logger.InfoWith("probe injected").
+				String("container", *container.Name).
+				String("probe", probeType).
+				Write()
+		}
+	}
+
+	response, err := kubewarden.MutatePodSpecFromRequest(validationRequest, podSpec)
+	if err != nil {
+		logger.WarnWith("cannot mutate pod spec").
+			Err("error", err).
+			Write()
+		return nil, false
+	}
+
+	// Only mutate the request when the injected probes fix every violation。
+	validationRequest.Request.Object = json.RawMessage(gjson.GetBytes(response, "mutated_object").Raw)
+	remaining, err := validateObject(validationRequest.Request, kind, settings, exempted)
+	if err != nil || !remaining.empty() {
+		return nil, false
+	}
+	return response, true
+}
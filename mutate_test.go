@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	kubewarden_protocol "github.com/kubewarden/policy-sdk-go/protocol"
+	"github.com/tidwall/gjson"
+)
+
+func TestMutateMissingProbes(t *testing.T) {
+	deployment := `{
+		"apiVersion": "apps/v1",
+		"kind": "Deployment",
+		"metadata": {"name": "app", "namespace": "default"},
+		"spec": {
+			"replicas": 2,
+			"selector": {"matchLabels": {"app": "app"}},
+			"template": {
+				"metadata": {"labels": {"app": "app"}},
+				"spec": {"containers": [
+					{"name": "app", "image": "nginx", "ports": [{"containerPort": 8080}, {"containerPort": 9090}]},
+					{"name": "sidecar", "image": "envoy", "ports": [{"containerPort": 15000}],
+						"readinessProbe": {"httpGet": {"path": "/ready", "port": 15000}}}
+				]}
+			}
+		}
+	}`
+
+	tests := []struct {
+		name             string
+		settings         string
+		object           string
+		shouldMutate     bool
+		expectedProbes   map[string]string
+		expectedRejected bool
+	}{
+		{
+			name:         "default tcpSocket probe on the first port",
+			settings:     `{"mutation": {"enabled": true}}`,
+			object:       deployment,
+			shouldMutate: true,
+			expectedProbes: map[string]string{
+				"app.readinessProbe": `{"tcpSocket":{"port":8080}}`,
+			},
+		},
+		{
+			name: "probe templates",
+			settings: `{"liveness_probe": {"required": true}, "mutation": {"enabled": true,
+				"liveness_probe": {"httpGet": {"path": "/{{container}}/healthz", "port": "{{port}}"}, "periodSeconds": 20},
+				"readiness_probe": {"exec": {"command": ["/bin/check", "{{container}}"]}}}}`,
+			object:       deployment,
+			shouldMutate: true,
+			expectedProbes: map[string]string{
+				"app.livenessProbe":      `{"httpGet":{"path":"/app/healthz","port":8080},"periodSeconds":20}`,
+				"app.readinessProbe":     `{"exec":{"command":["/bin/check","app"]}}`,
+				"sidecar.livenessProbe":  `{"httpGet":{"path":"/sidecar/healthz","port":15000},"periodSeconds":20}`,
+				"sidecar.readinessProbe": `{"httpGet":{"path":"/ready","port":15000}}`,
+			},
+		},
+		{
+			name:             "mutation disabled",
+			settings:         `{}`,
+			object:           deployment,
+			expectedRejected: true,
+		},
+		{
+			name:     "container without port",
+			settings: `{"mutation": {"enabled": true}}`,
+			object: `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "app"},
+				"spec": {"template": {"spec": {"containers": [{"name": "app", "image": "nginx"}]}}}}`,
+			expectedRejected: true,
+		},
+		{
+			name:             "injected probe violating the bounds",
+			settings:         `{"readiness_probe": {"required": true, "max_period_seconds": 5}, "mutation": {"enabled": true}}`,
+			object:           deployment,
+			expectedRejected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := kubewarden_protocol.ValidationRequest{
+				Request: kubewarden_protocol.KubernetesAdmissionRequest{
+					Operation: "CREATE",
+					Kind:      kubewarden_protocol.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+					Object:    json.RawMessage(test.object),
+				},
+				Settings: json.RawMessage(test.settings),
+			}
+
+			response := validateRequest(t, request)
+			if test.expectedRejected {
+				if response.Accepted || response.MutatedObject != nil {
+					t.Fatalf("Expected request to be rejected, got accepted: %v, mutated object: %v",
+						response.Accepted, response.MutatedObject)
+				}
+				return
+			}
+			if !response.Accepted || (response.MutatedObject != nil) != test.shouldMutate {
+				t.Fatalf("Expected accepted request with mutation %v, got accepted: %v, mutated object: %v",
+					test.shouldMutate, response.Accepted, response.MutatedObject)
+			}
+
+			mutated, err := json.Marshal(response.MutatedObject)
+			if err != nil {
+				t.Fatalf("Unexpected error: %+v", err)
+			}
+			containers := gjson.GetBytes(mutated, "spec.template.spec.containers")
+			for path, expected := range test.expectedProbes {
+				name, field, _ := strings.Cut(path, ".")
+				actual := containers.Get(`#(name=="` + name + `").` + field)
+				if !sameJSON(actual.Raw, expected) {
+					t.Errorf("Expected %s to be %s, got %s", path, expected, actual.Raw)
+				}
+			}
+
+			// The mutated object validates cleanly。
+			request.Request.Object = mutated
+			if revalidated := validateRequest(t, request); !revalidated.Accepted || revalidated.MutatedObject != nil {
+				t.Errorf("Expected mutated object to be accepted without mutation, got accepted: %v, mutated object: %v",
+					revalidated.Accepted, revalidated.MutatedObject)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/match"
+)
+
+// regexPatternPrefix marks a pattern as a regular expression instead of a glob。
+const regexPatternPrefix = "regex:"
+
+// matchPattern reports whether value matches pattern。Patterns prefixed with "regex:" are regular
+// expressions, any other pattern is a glob where '*' matches any sequence of characters and '?'
+// matches a single character。
+func matchPattern(pattern, value string) (bool, error) {
+	if expression, isRegex := strings.CutPrefix(pattern, regexPatternPrefix); isRegex {
+		matched, err := regexp.MatchString(expression, value)
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+		}
+		return matched, nil
+	}
+	return match.Match(value, pattern), nil
+}
+
+// firstMatchingPattern returns the first pattern matching value。
+func firstMatchingPattern(patterns []string, value string) (string, bool) {
+	for _, pattern := range patterns {
+		if matched, err := matchPattern(pattern, value); err == nil && matched {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// validatePatterns checks that every pattern can be compiled。
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if expression, isRegex := strings.CutPrefix(pattern, regexPatternPrefix); isRegex {
+			if _, err := regexp.Compile(expression); err != nil {
+				return fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
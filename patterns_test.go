@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		matched bool
+	}{
+		{"/ready*", "/ready", true},
+		{"/ready*", "/readyz/deep", true},
+		{"/ready*", "/healthz", false},
+		{"/health/?b", "/health/db", true},
+		{"*/actuator/*", "/app/actuator/health", true},
+		{"regex:^/health/(db|cache)$", "/health/db", true},
+		{"regex:^/health/(db|cache)$", "/health/dbx", false},
+		{"regex:^/ready", "/ready/live", true},
+	}
+
+	for _, test := range tests {
+		matched, err := matchPattern(test.pattern, test.value)
+		if err != nil {
+			t.Errorf("Unexpected error for pattern %q: %v", test.pattern, err)
+			continue
+		}
+		if matched != test.matched {
+			t.Errorf("Expected pattern %q matching %q to be %v, got %v",
+				test.pattern, test.value, test.matched, matched)
+		}
+	}
+}
+
+func TestValidatePatterns(t *testing.T) {
+	if err := validatePatterns([]string{"/ready*", "regex:^/health/.*$"}); err != nil {
+		t.Errorf("Expected patterns to be valid, got error: %v", err)
+	}
+
+	if err := validatePatterns([]string{"regex:^/health/(db"}); err == nil {
+		t.Error("Expected invalid regular expression to be rejected")
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"github.com/tidwall/gjson"
+)
+
+// weakestProbeHandler is the handler only checking that a port accepts connections。Switching an
+// existing probe to it is a downgrade。
+const weakestProbeHandler = "tcpSocket"
+
+// validateProbeRegressions compares the probes of every container of the object with the ones of
+// the container with the same name in the old object, and records the changes weakening them。
+// Containers missing from the old object, and containers exempt through their image or name, are
+// not compared。
+func validateProbeRegressions(kind string, objectJSON, oldObjectJSON []byte, settings Settings, found *violations) {
+	specPath, err := podSpecPath(kind)
+	if err != nil {
+		return
+	}
+	podSpec := gjson.GetBytes(objectJSON, specPath)
+	oldPodSpec := gjson.GetBytes(oldObjectJSON, specPath)
+
+	// A missing default profile is reported by validateDeployment。
+	labelValue := profileLabelValue(objectJSON, specPath, settings.ProfileLabel)
+	if _, profile, profileErr := settings.profile(labelValue); profileErr == nil && profile != nil {
+		settings = profile.apply(settings)
+	}
+
+	oldContainers := map[string]gjson.Result{}
+	oldPodSpec.Get("containers").ForEach(func(_, container gjson.Result) bool {
+		oldContainers[container.Get("name").String()] = container
+		return true
+	})
+
+	podSpec.Get("containers").ForEach(func(_, container gjson.Result) bool {
+		containerName := container.Get("name").String()
+		oldContainer, exists := oldContainers[containerName]
+		if !exists {
+			return true
+		}
+		containerSettings, exempt := resolveContainerSettings(containerName, container.Get("image").String(), settings)
+		if exempt {
+			return true
+		}
+		configs := map[string]ProbeConfig{
+			"liveness":  containerSettings.LivenessProbe,
+			"readiness": containerSettings.ReadinessProbe,
+			"startup":   containerSettings.StartupProbe,
+		}
+		for _, probeType := range probeTypes {
+			validateProbeRegression(container.Get(probeType+"Probe"), podSpec,
+				oldContainer.Get(probeType+"Probe"), oldPodSpec, probeType, containerName, configs[probeType], found)
+		}
+		return true
+	})
+}
+
+// validateProbeRegression records the changes weakening a probe compared to its previous version,
+// limited to the dimensions the probe configuration constrains:
+//   - removing the probe, when the probe is configured at all;
+//   - switching to a tcpSocket handler, when the probe is configured at all;
+//   - a longer failure window, when the period, the failure threshold or the detection time is bounded;
+//   - a longer timeout, when the timeout, the detection time or the timeout to period ratio is bounded;
+//   - a longer initial delay, when the initial delay is bounded。
+func validateProbeRegression(probe, podSpec, oldProbe, oldPodSpec gjson.Result, probeType, containerName string,
+	config ProbeConfig, found *violations) {
+	if !oldProbe.Exists() {
+		return
+	}
+	if !probe.Exists() {
+		if config.configured() {
+			found.add(containerName, probeType, "%s probe was removed", probeType)
+		}
+		return
+	}
+
+	oldHandler, oldErr := probeHandler(oldProbe)
+	handler, err := probeHandler(probe)
+	if err != nil {
+		return
+	}
+	config = config.forHandler(handler)
+
+	if config.configured() && oldErr == nil && handler != oldHandler && handler == weakestProbeHandler {
+		found.add(containerName, probeType, "%s probe handler was downgraded from %s to %s",
+			probeType, oldHandler, handler)
+	}
+
+	oldTimings := effectiveProbeTimings(oldProbe, oldPodSpec)
+	timings := effectiveProbeTimings(probe, podSpec)
+	boundsFailureWindow := config.MinPeriodSeconds > 0 || config.MaxPeriodSeconds > 0 ||
+		config.MinFailureThreshold > 0 || config.MaxFailureThreshold > 0 || config.MaxDetectionSeconds > 0
+	if boundsFailureWindow && timings.failureWindow() > oldTimings.failureWindow() {
+		found.add(containerName, probeType,
+			"%s probe failure window increased from %ds (periodSeconds %d × failureThreshold %d) "+
+				"to %ds (periodSeconds %d × failureThreshold %d)",
+			probeType, oldTimings.failureWindow(), oldTimings.PeriodSeconds, oldTimings.FailureThreshold,
+			timings.failureWindow(), timings.PeriodSeconds, timings.FailureThreshold)
+	}
+	boundsTimeout := config.MinTimeoutSeconds > 0 || config.MaxTimeoutSeconds > 0 ||
+		config.MaxDetectionSeconds > 0 || config.MaxTimeoutToPeriodRatio > 0
+	if boundsTimeout && timings.TimeoutSeconds > oldTimings.TimeoutSeconds {
+		found.add(containerName, probeType, "%s probe timeoutSeconds increased from %ds to %ds",
+			probeType, oldTimings.TimeoutSeconds, timings.TimeoutSeconds)
+	}
+	boundsInitialDelay := config.MinInitialDelaySeconds > 0 || config.MaxInitialDelaySeconds > 0
+	if boundsInitialDelay && timings.InitialDelaySeconds > oldTimings.InitialDelaySeconds {
+		found.add(containerName, probeType, "%s probe initialDelaySeconds increased from %ds to %ds",
+			probeType, oldTimings.InitialDelaySeconds, timings.InitialDelaySeconds)
+	}
+}
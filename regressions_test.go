@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	kubewarden_protocol "github.com/kubewarden/policy-sdk-go/protocol"
+)
+
+func TestValidateProbeRegressions(t *testing.T) {
+	oldContainer := `{"name": "app",
+		"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "periodSeconds": 10, "timeoutSeconds": 2},
+		"readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}}`
+
+	settings := Settings{
+		LivenessProbe:  ProbeConfig{MaxPeriodSeconds: 30, MaxTimeoutSeconds: 10},
+		ReadinessProbe: ProbeConfig{ForbiddenHandlers: []string{"exec"}},
+	}
+
+	tests := []struct {
+		name            string
+		container       string
+		settings        *Settings
+		expectedMessage string
+	}{
+		{
+			name:      "unchanged probes",
+			container: oldContainer,
+		},
+		{
+			name: "stricter probes",
+			container: `{"name": "app",
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "periodSeconds": 5, "timeoutSeconds": 1},
+				"readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}},
+				"startupProbe": {"httpGet": {"path": "/healthz", "port": 8080}}}`,
+		},
+		{
+			name: "removed probe",
+			container: `{"name": "app",
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "periodSeconds": 10, "timeoutSeconds": 2}}`,
+			expectedMessage: "container 'app': readiness probe was removed",
+		},
+		{
+			name: "handler downgrade",
+			container: `{"name": "app",
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "periodSeconds": 10, "timeoutSeconds": 2},
+				"readinessProbe": {"tcpSocket": {"port": 8080}}}`,
+			expectedMessage: "container 'app': readiness probe handler was downgraded from httpGet to tcpSocket",
+		},
+		{
+			name: "longer failure window and timeout",
+			container: `{"name": "app",
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "periodSeconds": 10,
+					"failureThreshold": 6, "timeoutSeconds": 5},
+				"readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}}`,
+			expectedMessage: "container 'app': liveness probe failure window increased from 30s " +
+				"(periodSeconds 10 × failureThreshold 3) to 60s (periodSeconds 10 × failureThreshold 6); " +
+				"liveness probe timeoutSeconds increased from 2s to 5s",
+		},
+		{
+			name: "unconstrained dimensions are not compared",
+			container: `{"name": "app",
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "periodSeconds": 10,
+					"failureThreshold": 6, "timeoutSeconds": 5}}`,
+			settings: &Settings{LivenessProbe: ProbeConfig{Required: true}},
+		},
+		{
+			name:      "exempt container is not compared",
+			container: `{"name": "app"}`,
+			settings: &Settings{
+				LivenessProbe:      ProbeConfig{Required: true},
+				ContainerOverrides: []ContainerOverride{{Name: "app", Exempt: true}},
+			},
+		},
+		{
+			name:      "renamed container is not compared",
+			container: `{"name": "web"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			object := []byte(`{"kind": "Deployment", "spec": {"template": {"spec": {"containers": [` +
+				test.container + `]}}}}`)
+			oldObject := []byte(`{"kind": "Deployment", "spec": {"template": {"spec": {"containers": [` +
+				oldContainer + `]}}}}`)
+
+			testSettings := settings
+			if test.settings != nil {
+				testSettings = *test.settings
+			}
+			found := &violations{}
+			validateProbeRegressions("Deployment", object, oldObject, testSettings, found)
+			if message := found.message(0); message != test.expectedMessage {
+				t.Errorf("Expected message %q, got %q", test.expectedMessage, message)
+			}
+		})
+	}
+}
+
+func TestValidateNoRegressionOnUpdate(t *testing.T) {
+	settings := `{"readiness_probe": {"required": false, "max_period_seconds": 30},
+		"grandfather_on_update": true, "no_regression": true}`
+	oldObject := `{"kind": "Deployment", "spec": {"template": {"spec": {"containers": [
+		{"name": "app", "readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}}
+	]}}}}`
+	object := `{"kind": "Deployment", "spec": {"template": {"spec": {"containers": [{"name": "app"}]}}}}`
+
+	tests := []struct {
+		name        string
+		operation   string
+		shouldAllow bool
+	}{
+		{name: "update", operation: "UPDATE", shouldAllow: false},
+		{name: "create", operation: "CREATE", shouldAllow: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := kubewarden_protocol.ValidationRequest{
+				Request: kubewarden_protocol.KubernetesAdmissionRequest{
+					Operation: test.operation,
+					Object:    json.RawMessage(object),
+					OldObject: json.RawMessage(oldObject),
+				},
+				Settings: json.RawMessage(settings),
+			}
+
+			response := validateRequest(t, request)
+			if response.Accepted != test.shouldAllow {
+				t.Errorf("Expected validation to return %v, got %v. Message: %v",
+					test.shouldAllow, response.Accepted, response.Message)
+			}
+		})
+	}
+}
+
+func TestValidateNoRegressionExemptContainer(t *testing.T) {
+	settings := `{"readiness_probe": {"required": false, "max_period_seconds": 30}, "no_regression": true,
+		"container_overrides": [{"name": "istio-proxy", "exempt": true}]}`
+	oldObject := `{"kind": "Deployment", "spec": {"template": {"spec": {"containers": [
+		{"name": "app", "readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}},
+		{"name": "istio-proxy", "readinessProbe": {"httpGet": {"path": "/healthz/ready", "port": 15021}}}
+	]}}}}`
+
+	tests := []struct {
+		name        string
+		object      string
+		shouldAllow bool
+	}{
+		{
+			name: "exempt container removes its probe",
+			object: `{"kind": "Deployment", "spec": {"template": {"spec": {"containers": [
+				{"name": "app", "readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}},
+				{"name": "istio-proxy"}
+			]}}}}`,
+			shouldAllow: true,
+		},
+		{
+			name: "enforced container removes its probe",
+			object: `{"kind": "Deployment", "spec": {"template": {"spec": {"containers": [
+				{"name": "app"},
+				{"name": "istio-proxy", "readinessProbe": {"httpGet": {"path": "/healthz/ready", "port": 15021}}}
+			]}}}}`,
+			shouldAllow: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := kubewarden_protocol.ValidationRequest{
+				Request: kubewarden_protocol.KubernetesAdmissionRequest{
+					Operation: "UPDATE",
+					Object:    json.RawMessage(test.object),
+					OldObject: json.RawMessage(oldObject),
+				},
+				Settings: json.RawMessage(settings),
+			}
+
+			response := validateRequest(t, request)
+			if response.Accepted != test.shouldAllow {
+				t.Errorf("Expected validation to return %v, got %v. Message: %v",
+					test.shouldAllow, response.Accepted, response.Message)
+			}
+		})
+	}
+}
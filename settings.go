@@ -2,7 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 
 	kubewarden "github.com/kubewarden/policy-sdk-go"
 	kubewarden_protocol "github.com/kubewarden/policy-sdk-go/protocol"
@@ -16,18 +20,460 @@ type Settings struct {
 	ReadinessProbe ProbeConfig `json:"readiness_probe"`
 	// StartupProbe specifies the requirements for startup probe configuration。
 	StartupProbe ProbeConfig `json:"startup_probe"`
+	// ProbePorts specifies the check of the ports targeted by the probes。
+	ProbePorts ProbePortsConfig `json:"probe_ports"`
+	// HTTPProbeSecurity specifies the security rules for httpGet probes。
+	HTTPProbeSecurity HTTPProbeSecurityConfig `json:"http_probe_security"`
+	// ExecProbe specifies the constraints on the commands of exec probes。
+	ExecProbe ExecProbeConfig `json:"exec_probe"`
+	// ProbeRelations specifies the rules comparing the probes of a container。
+	ProbeRelations ProbeRelationsConfig `json:"probe_relations"`
+	// MaxStartupSeconds specifies the longest startup, in seconds, containers must be allowed before the
+	// kubelet considers them failed。
+	MaxStartupSeconds int32 `json:"max_startup_seconds,omitempty"`
+	// ContainerOverrides specifies different requirements for the containers matching a name
+	// pattern, such as sidecars。The first matching override applies。
+	ContainerOverrides []ContainerOverride `json:"container_overrides,omitempty"`
+	// ImageRules specifies different requirements for the containers whose image matches a pattern。
+	// Every matching rule applies, in order, before the container overrides。
+	ImageRules []ImageRule `json:"image_rules,omitempty"`
+	// ExcludedNamespaces lists the glob or "regex:" patterns of the namespaces the policy ignores。
+	ExcludedNamespaces []string `json:"excluded_namespaces,omitempty"`
+	// IncludedNamespaces lists the glob or "regex:" patterns of the namespaces the policy enforces。
+	// When empty, every namespace not excluded is enforced。
+	IncludedNamespaces []string `json:"included_namespaces,omitempty"`
+	// NamespaceOverlays specifies different requirements for the namespaces matching a pattern。
+	// The first matching overlay applies, before the image rules and the container overrides。
+	NamespaceOverlays []NamespaceOverlay `json:"namespace_overlays,omitempty"`
+	// Profiles defines named sets of probe configurations, for instance one for web services and
+	// one for workers。A profile replaces the probe configurations it sets。
+	Profiles map[string]ProbeOverrides `json:"profiles,omitempty"`
+	// ProfileLabel is the workload or pod template label selecting the profile, e.g. "workload-class"。
+	ProfileLabel string `json:"profile_label,omitempty"`
+	// DefaultProfile is the profile used when the workload has no profile label。
+	DefaultProfile string `json:"default_profile,omitempty"`
+	// ExemptUsers lists the glob or "regex:" patterns of the usernames whose requests bypass the
+	// policy, e.g. break-glass operators。
+	ExemptUsers []string `json:"exempt_users,omitempty"`
+	// ExemptGroups lists the glob or "regex:" patterns of the groups whose members bypass the policy。
+	ExemptGroups []string `json:"exempt_groups,omitempty"`
+	// ExemptServiceAccounts lists the glob or "regex:" patterns of the service accounts whose
+	// requests bypass the policy, either "namespace:name" (e.g. "argocd:*") or the full username
+	// (e.g. "system:serviceaccount:argocd:*")。
+	ExemptServiceAccounts []string `json:"exempt_service_accounts,omitempty"`
+	// GrandfatherOnUpdate accepts, on UPDATE, the violations the old object already had。
+	GrandfatherOnUpdate bool `json:"grandfather_on_update,omitempty"`
+	// NoRegression rejects, on UPDATE, the changes weakening the probes of the containers already
+	// present in the old object, even when their violations are grandfathered。Only the dimensions the
+	// probe configuration of the container constrains are compared, and exempt containers are skipped。
+	NoRegression bool `json:"no_regression,omitempty"`
+	// EnforcedOperations lists the admission operations the policy validates (CREATE, UPDATE)。The
+	// other operations are accepted。The list cannot be empty。
+	EnforcedOperations []string `json:"enforced_operations,omitempty"`
+	// DryRunFullMessage returns every violation of dry-run requests, without capping the message。
+	DryRunFullMessage bool `json:"dry_run_full_message,omitempty"`
+	// Mutation injects the missing required probes instead of rejecting the request。The policy
+	// must be deployed as a mutating policy。
+	Mutation MutationConfig `json:"mutation"`
+	// AllowExemptionAnnotations honors the probes-check.kubewarden.io/exempt annotations on the
+	// workload or its pod template。
+	AllowExemptionAnnotations bool `json:"allow_exemption_annotations,omitempty"`
+	// MaxExemptionDays is the longest time, in days from today, an exemption annotation may last。
+	MaxExemptionDays int `json:"max_exemption_days,omitempty"`
+}
+
+// ProbeOverrides represents probe configurations replacing the ones of the base settings。A
+// configured probe replaces the whole base configuration of that probe, the fields are not merged
+// one by one。A probe without configuration keeps the base one。
+type ProbeOverrides struct {
+	// LivenessProbe replaces the liveness probe configuration。
+	LivenessProbe *ProbeConfig `json:"liveness_probe,omitempty"`
+	// ReadinessProbe replaces the readiness probe configuration。
+	ReadinessProbe *ProbeConfig `json:"readiness_probe,omitempty"`
+	// StartupProbe replaces the startup probe configuration。
+	StartupProbe *ProbeConfig `json:"startup_probe,omitempty"`
+}
+
+// ContainerOverride represents the probe requirements for the containers matching a name pattern。
+type ContainerOverride struct {
+	// Name is the glob or "regex:" pattern matched against the container name。
+	Name string `json:"name"`
+	// Exempt skips every probe check for the matching containers。
+	Exempt bool `json:"exempt,omitempty"`
+	ProbeOverrides
+}
+
+// ImageRule represents the probe requirements for the containers running a matching image。
+type ImageRule struct {
+	// Image is the glob or "regex:" pattern matched against the container image, for instance
+	// "*/openjdk*" or "registry.example.com/vendor/*"。
+	Image string `json:"image"`
+	// IgnoreTag matches the pattern against the image without its tag and digest。
+	IgnoreTag bool `json:"ignore_tag,omitempty"`
+	// Exempt skips every probe check for the matching containers。
+	Exempt bool `json:"exempt,omitempty"`
+	ProbeOverrides
+}
+
+// NamespaceOverlay represents the probe requirements for the namespaces matching a pattern。
+type NamespaceOverlay struct {
+	// Namespaces lists the glob or "regex:" patterns matched against the request namespace。
+	Namespaces []string `json:"namespaces"`
+	ProbeOverrides
+}
+
+// MutationConfig represents the probes injected into the containers missing a required probe。
+type MutationConfig struct {
+	// Enabled injects the missing required probes。
+	Enabled bool `json:"enabled"`
+	// LivenessProbe is the template of the injected liveness probe, a Kubernetes probe where the
+	// {{port}} and {{container}} placeholders are replaced by the first port declared by the
+	// container and by its name。Defaults to a tcpSocket probe on {{port}}。
+	LivenessProbe json.RawMessage `json:"liveness_probe,omitempty"`
+	// ReadinessProbe is the template of the injected readiness probe。
+	ReadinessProbe json.RawMessage `json:"readiness_probe,omitempty"`
+	// StartupProbe is the template of the injected startup probe。
+	StartupProbe json.RawMessage `json:"startup_probe,omitempty"`
+}
+
+// ProbePortsConfig represents the requirements on the ports targeted by the probes。
+type ProbePortsConfig struct {
+	// Enabled checks that httpGet, tcpSocket and grpc probes target a port declared by the container。
+	Enabled bool `json:"enabled"`
+	// AllowOtherContainers accepts numeric ports declared by other containers of the same pod。
+	AllowOtherContainers bool `json:"allow_other_containers"`
+}
+
+// HTTPProbeSecurityConfig represents the security requirements for httpGet probes。
+type HTTPProbeSecurityConfig struct {
+	// ForbidHost rejects httpGet probes that set a host, unless it matches AllowedHosts。
+	ForbidHost bool `json:"forbid_host"`
+	// ForbidHostHeader rejects Host header overrides, unless the value matches AllowedHosts。
+	ForbidHostHeader bool `json:"forbid_host_header"`
+	// AllowedHosts lists the glob or "regex:" patterns of the hosts probes may target。
+	AllowedHosts []string `json:"allowed_hosts,omitempty"`
+	// SensitiveHeaders lists the header names, compared case-insensitively, probes must not set。
+	SensitiveHeaders []string `json:"sensitive_headers,omitempty"`
+	// RequireHTTPS rejects httpGet probes that do not use the HTTPS scheme。
+	RequireHTTPS bool `json:"require_https"`
+}
+
+// ExecProbeConfig represents the requirements for the commands of exec probes。
+type ExecProbeConfig struct {
+	// ForbidShellWrappers rejects commands running a shell with an inline script, such as "sh -c"。
+	ForbidShellWrappers bool `json:"forbid_shell_wrappers"`
+	// AllowedCommands lists the glob or "regex:" patterns the first argument of the command, or
+	// its base name, must match。
+	AllowedCommands []string `json:"allowed_commands,omitempty"`
+	// MaxCommandLength specifies the maximum length of the command, arguments joined by spaces。
+	MaxCommandLength int32 `json:"max_command_length,omitempty"`
+}
+
+// ProbeRelationsConfig represents the rules comparing the liveness, readiness and startup probes of
+// a container。
+type ProbeRelationsConfig struct {
+	// LivenessSlowerThanReadiness requires the liveness failure window (periodSeconds × failureThreshold)
+	// to be at least the readiness one, so pods leave the Service endpoints before being restarted。
+	LivenessSlowerThanReadiness bool `json:"liveness_slower_than_readiness"`
+	// DistinctLivenessReadiness rejects liveness and readiness probes that are identical。
+	DistinctLivenessReadiness bool `json:"distinct_liveness_readiness"`
+	// StartupMatchesLivenessPort requires the startup probe to target the same port as the liveness probe。
+	StartupMatchesLivenessPort bool `json:"startup_matches_liveness_port"`
 }
 
 // ProbeConfig represents the configuration requirements for a probe。
 type ProbeConfig struct {
 	// Required indicates whether the probe must be configured in the deployment。
 	Required bool `json:"required"`
+	// RequiredWhenReplicasAtLeast only requires the probe when the workload asks for at least this
+	// many replicas。Workloads without a replica count, for instance the ones managed by a
+	// HorizontalPodAutoscaler, and the kinds without replicas still require the probe。
+	RequiredWhenReplicasAtLeast int32 `json:"required_when_replicas_at_least,omitempty"`
 	// MinPeriodSeconds specifies the minimum allowed period between probe executions (in seconds)。
 	MinPeriodSeconds int32 `json:"min_period_seconds,omitempty"`
+	// MaxPeriodSeconds specifies the maximum allowed period between probe executions (in seconds)。
+	MaxPeriodSeconds int32 `json:"max_period_seconds,omitempty"`
+	// MinTimeoutSeconds specifies the minimum allowed timeout for probe execution (in seconds)。
+	MinTimeoutSeconds int32 `json:"min_timeout_seconds,omitempty"`
 	// MaxTimeoutSeconds specifies the maximum allowed timeout for probe execution (in seconds)。
 	MaxTimeoutSeconds int32 `json:"max_timeout_seconds,omitempty"`
+	// MinInitialDelaySeconds specifies the minimum allowed delay before the first probe (in seconds)。
+	MinInitialDelaySeconds int32 `json:"min_initial_delay_seconds,omitempty"`
+	// MaxInitialDelaySeconds specifies the maximum allowed delay before the first probe (in seconds)。
+	MaxInitialDelaySeconds int32 `json:"max_initial_delay_seconds,omitempty"`
+	// MinFailureThreshold specifies the minimum allowed number of consecutive failures。
+	MinFailureThreshold int32 `json:"min_failure_threshold,omitempty"`
+	// MaxFailureThreshold specifies the maximum allowed number of consecutive failures。
+	MaxFailureThreshold int32 `json:"max_failure_threshold,omitempty"`
+	// MinSuccessThreshold specifies the minimum allowed number of consecutive successes。
+	MinSuccessThreshold int32 `json:"min_success_threshold,omitempty"`
+	// MaxSuccessThreshold specifies the maximum allowed number of consecutive successes。
+	MaxSuccessThreshold int32 `json:"max_success_threshold,omitempty"`
+	// MinTerminationGracePeriodSeconds specifies the minimum allowed probe-level termination grace
+	// period (in seconds)。Not supported for readiness probes。
+	MinTerminationGracePeriodSeconds int32 `json:"min_termination_grace_period_seconds,omitempty"`
+	// MaxTerminationGracePeriodSeconds specifies the maximum allowed probe-level termination grace
+	// period (in seconds)。Not supported for readiness probes。
+	MaxTerminationGracePeriodSeconds int32 `json:"max_termination_grace_period_seconds,omitempty"`
+	// MaxDetectionSeconds specifies the maximum worst-case time to detect a broken container
+	// (periodSeconds × failureThreshold + timeoutSeconds)。
+	MaxDetectionSeconds int32 `json:"max_detection_seconds,omitempty"`
+	// MaxTimeoutToPeriodRatio specifies the maximum allowed timeoutSeconds / periodSeconds ratio。
+	MaxTimeoutToPeriodRatio float64 `json:"max_timeout_to_period_ratio,omitempty"`
+
+	// HTTP overrides the timing bounds for httpGet probes。Only timing bounds can be set。
+	HTTP *ProbeConfig `json:"http,omitempty"`
+	// TCP overrides the timing bounds for tcpSocket probes。Only timing bounds can be set。
+	TCP *ProbeConfig `json:"tcp,omitempty"`
+	// Exec overrides the timing bounds for exec probes。Only timing bounds can be set。
+	Exec *ProbeConfig `json:"exec,omitempty"`
+	// GRPC overrides the timing bounds for grpc probes。Only timing bounds can be set。
+	GRPC *ProbeConfig `json:"grpc,omitempty"`
+	// RequireExplicitTimings rejects probes that rely on the Kubernetes defaults for
+	// initialDelaySeconds, periodSeconds, timeoutSeconds, successThreshold or failureThreshold。
+	RequireExplicitTimings bool `json:"require_explicit_timings,omitempty"`
+	// AllowedHandlers restricts the probe to these handlers (httpGet, tcpSocket, exec, grpc)。
+	AllowedHandlers []string `json:"allowed_handlers,omitempty"`
+	// ForbiddenHandlers lists the handlers (httpGet, tcpSocket, exec, grpc) the probe must not use。
+	ForbiddenHandlers []string `json:"forbidden_handlers,omitempty"`
+	// AllowedPaths lists the glob or "regex:" patterns the httpGet path must match。
+	AllowedPaths []string `json:"allowed_paths,omitempty"`
+	// ForbiddenPaths lists the glob or "regex:" patterns the httpGet path must not match。
+	ForbiddenPaths []string `json:"forbidden_paths,omitempty"`
+}
+
+// configured reports whether the configuration requires or constrains the probe in any way。
+func (c ProbeConfig) configured() bool {
+	return !reflect.DeepEqual(c, ProbeConfig{})
+}
+
+// requiredFor reports whether the probe is required for a workload with the given replica count,
+// unknownReplicas when the workload has none。
+func (c ProbeConfig) requiredFor(replicas int64) bool {
+	if !c.Required {
+		return false
+	}
+	if c.RequiredWhenReplicasAtLeast == 0 || replicas == unknownReplicas {
+		return true
+	}
+	return replicas >= int64(c.RequiredWhenReplicasAtLeast)
+}
+
+// handlerConfigs returns the per-handler timing overrides, keyed by handler name。
+func (c ProbeConfig) handlerConfigs() map[string]*ProbeConfig {
+	return map[string]*ProbeConfig{
+		"httpGet":   c.HTTP,
+		"tcpSocket": c.TCP,
+		"exec":      c.Exec,
+		"grpc":      c.GRPC,
+	}
+}
+
+// forHandler returns the configuration to apply to a probe using the given handler: the timing
+// bounds set in the handler section override the probe-level ones, the others are inherited。
+func (c ProbeConfig) forHandler(handler string) ProbeConfig {
+	override := c.handlerConfigs()[handler]
+	if override == nil {
+		return c
+	}
+	return c.withTimingBounds(*override)
+}
+
+// withTimingBounds returns a copy of the configuration where every timing bound set in override
+// replaces the current one。
+func (c ProbeConfig) withTimingBounds(override ProbeConfig) ProbeConfig {
+	merged := c
+	for _, field := range []struct{ target, value *int32 }{
+		{&merged.MinPeriodSeconds, &override.MinPeriodSeconds},
+		{&merged.MaxPeriodSeconds, &override.MaxPeriodSeconds},
+		{&merged.MinTimeoutSeconds, &override.MinTimeoutSeconds},
+		{&merged.MaxTimeoutSeconds, &override.MaxTimeoutSeconds},
+		{&merged.MinInitialDelaySeconds, &override.MinInitialDelaySeconds},
+		{&merged.MaxInitialDelaySeconds, &override.MaxInitialDelaySeconds},
+		{&merged.MinFailureThreshold, &override.MinFailureThreshold},
+		{&merged.MaxFailureThreshold, &override.MaxFailureThreshold},
+		{&merged.MinSuccessThreshold, &override.MinSuccessThreshold},
+		{&merged.MaxSuccessThreshold, &override.MaxSuccessThreshold},
+		{&merged.MinTerminationGracePeriodSeconds, &override.MinTerminationGracePeriodSeconds},
+		{&merged.MaxTerminationGracePeriodSeconds, &override.MaxTerminationGracePeriodSeconds},
+		{&merged.MaxDetectionSeconds, &override.MaxDetectionSeconds},
+	} {
+		if *field.value != 0 {
+			*field.target = *field.value
+		}
+	}
+	if override.MaxTimeoutToPeriodRatio != 0 {
+		merged.MaxTimeoutToPeriodRatio = override.MaxTimeoutToPeriodRatio
+	}
+	return merged
+}
+
+// apply returns a copy of the settings where the configured probes are replaced。
+func (o ProbeOverrides) apply(settings Settings) Settings {
+	if o.LivenessProbe != nil {
+		settings.LivenessProbe = *o.LivenessProbe
+	}
+	if o.ReadinessProbe != nil {
+		settings.ReadinessProbe = *o.ReadinessProbe
+	}
+	if o.StartupProbe != nil {
+		settings.StartupProbe = *o.StartupProbe
+	}
+	return settings
+}
+
+// overProfiles returns the profiles with the configured probes replacing theirs, so that these
+// probes take precedence over whichever profile is applied later。
+func (o ProbeOverrides) overProfiles(profiles map[string]ProbeOverrides) map[string]ProbeOverrides {
+	overridden := make(map[string]ProbeOverrides, len(profiles))
+	for name, profile := range profiles {
+		if o.LivenessProbe != nil {
+			profile.LivenessProbe = o.LivenessProbe
+		}
+		if o.ReadinessProbe != nil {
+			profile.ReadinessProbe = o.ReadinessProbe
+		}
+		if o.StartupProbe != nil {
+			profile.StartupProbe = o.StartupProbe
+		}
+		overridden[name] = profile
+	}
+	return overridden
+}
+
+// validate validates the configured probes。
+func (o ProbeOverrides) validate(s *Settings, prefix string) error {
+	for _, probe := range []struct {
+		probeType string
+		config    *ProbeConfig
+	}{
+		{"liveness", o.LivenessProbe},
+		{"readiness", o.ReadinessProbe},
+		{"startup", o.StartupProbe},
+	} {
+		if probe.config == nil {
+			continue
+		}
+		if err := s.validateProbeConfig(probe.probeType, prefix+probe.probeType+" probe", *probe.config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// containerOverride returns the first container override matching the container name。
+func (s *Settings) containerOverride(containerName string) *ContainerOverride {
+	for i := range s.ContainerOverrides {
+		if matched, err := matchPattern(s.ContainerOverrides[i].Name, containerName); err == nil && matched {
+			return &s.ContainerOverrides[i]
+		}
+	}
+	return nil
+}
+
+// matchingImageRules returns the image rules matching the image, in the order they are listed。
+func (s *Settings) matchingImageRules(image string) []ImageRule {
+	rules := []ImageRule{}
+	for _, rule := range s.ImageRules {
+		candidate := image
+		if rule.IgnoreTag {
+			candidate = imageRepository(image)
+		}
+		if matched, err := matchPattern(rule.Image, candidate); err == nil && matched {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// imageRepository returns the image reference without its tag and digest, keeping the registry port。
+func imageRepository(image string) string {
+	if at := strings.Index(image, "@"); at >= 0 {
+		image = image[:at]
+	}
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		image = image[:colon]
+	}
+	return image
+}
+
+// enforcesNamespace reports whether the policy applies to the given namespace。Exclusions take
+// precedence over inclusions。
+func (s *Settings) enforcesNamespace(namespace string) bool {
+	if _, excluded := firstMatchingPattern(s.ExcludedNamespaces, namespace); excluded {
+		return false
+	}
+	if len(s.IncludedNamespaces) == 0 {
+		return true
+	}
+	_, included := firstMatchingPattern(s.IncludedNamespaces, namespace)
+	return included
+}
+
+// namespaceOverlay returns the first namespace overlay matching the namespace。
+func (s *Settings) namespaceOverlay(namespace string) *NamespaceOverlay {
+	for i := range s.NamespaceOverlays {
+		if _, matched := firstMatchingPattern(s.NamespaceOverlays[i].Namespaces, namespace); matched {
+			return &s.NamespaceOverlays[i]
+		}
+	}
+	return nil
+}
+
+// profile returns the name and the probe configurations of the profile selected by the label
+// value, falling back to the default profile when the value is empty or names no profile。The name
+// is empty when no profile applies。
+func (s *Settings) profile(labelValue string) (string, *ProbeOverrides, error) {
+	name := labelValue
+	if _, found := s.Profiles[name]; s.ProfileLabel == "" || !found {
+		name = s.DefaultProfile
+	}
+	if name == "" {
+		return "", nil, nil
+	}
+
+	profile, found := s.Profiles[name]
+	if !found {
+		return "", nil, fmt.Errorf("default_profile '%s' is not defined in profiles", name)
+	}
+	return name, &profile, nil
+}
+
+// templateValidationPort is the port the probe templates are rendered with to validate them。
+const templateValidationPort = 8080
+
+// admissionOperations lists the operations the policy can enforce。DELETE and CONNECT requests
+// carry no object to validate and are always accepted。
+//
+//nolint:gochecknoglobals // Read-only lookup table.
+var admissionOperations = []string{"CREATE", "UPDATE"}
+
+// serviceAccountUserPrefix is the username prefix of the service accounts。
+const serviceAccountUserPrefix = "system:serviceaccount:"
+
+// exemptRequester returns a description of the setting exempting the requesting user, if any。
+func (s *Settings) exemptRequester(userInfo kubewarden_protocol.UserInfo) (string, bool) {
+	if pattern, matched := firstMatchingPattern(s.ExemptUsers, userInfo.Username); matched {
+		return fmt.Sprintf("exempt_users '%s'", pattern), true
+	}
+	for _, group := range userInfo.Groups {
+		if pattern, matched := firstMatchingPattern(s.ExemptGroups, group); matched {
+			return fmt.Sprintf("exempt_groups '%s'", pattern), true
+		}
+	}
+	if serviceAccount, found := strings.CutPrefix(userInfo.Username, serviceAccountUserPrefix); found {
+		for _, name := range []string{serviceAccount, userInfo.Username} {
+			if pattern, matched := firstMatchingPattern(s.ExemptServiceAccounts, name); matched {
+				return fmt.Sprintf("exempt_service_accounts '%s'", pattern), true
+			}
+		}
+	}
+	return "", false
 }
 
+// defaultMaxExemptionDays is the default longest time, in days, an exemption annotation may last。
+const defaultMaxExemptionDays = 90
+
 // DefaultSettings returns default settings。
 func DefaultSettings() *Settings {
 	return &Settings{
@@ -40,6 +486,8 @@ func DefaultSettings() *Settings {
 		StartupProbe: ProbeConfig{
 			Required: false,
 		},
+		EnforcedOperations: []string{"CREATE", "UPDATE"},
+		MaxExemptionDays:   defaultMaxExemptionDays,
 	}
 }
 
@@ -58,36 +506,279 @@ func (s *Settings) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	// An explicit null keeps the default enforced operations。
+	if s.EnforcedOperations == nil {
+		s.EnforcedOperations = defaults.EnforcedOperations
+	}
+
 	return nil
 }
 
 // Validate validates the Settings configuration。
 func (s *Settings) Validate() error {
+	for _, validateSection := range []func() error{
+		s.validateProbes,
+		s.validateContainerRules,
+		s.validateProfiles,
+		s.validateMutation,
+		s.validateEnforcedOperations,
+		s.validateExemptions,
+		s.validateNamespaces,
+		s.validateProbeSecurity,
+	} {
+		if err := validateSection(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateProbes validates the liveness, readiness and startup probe configurations。
+func (s *Settings) validateProbes() error {
 	// Validate liveness probe configuration。
-	if err := s.validateProbeConfig("liveness probe", s.LivenessProbe); err != nil {
+	if err := s.validateProbeConfig("liveness", "liveness probe", s.LivenessProbe); err != nil {
 		return err
 	}
 
 	// Validate readiness probe configuration。
-	if err := s.validateProbeConfig("readiness probe", s.ReadinessProbe); err != nil {
+	if err := s.validateProbeConfig("readiness", "readiness probe", s.ReadinessProbe); err != nil {
 		return err
 	}
 
 	// Validate startup probe configuration。
-	if err := s.validateProbeConfig("startup probe", s.StartupProbe); err != nil {
+	if err := s.validateProbeConfig("startup", "startup probe", s.StartupProbe); err != nil {
 		return err
 	}
 
+	if s.MaxStartupSeconds < 0 {
+		return errors.New("max_startup_seconds must be non-negative")
+	}
+	return nil
+}
+
+// validateContainerRules validates the container overrides and the image rules。
+func (s *Settings) validateContainerRules() error {
+	// Validate container overrides。
+	for _, override := range s.ContainerOverrides {
+		if override.Name == "" {
+			return errors.New("container override: name is required")
+		}
+		if err := validatePatterns([]string{override.Name}); err != nil {
+			return fmt.Errorf("container override: %w", err)
+		}
+		if err := override.validate(s, fmt.Sprintf("container override '%s': ", override.Name)); err != nil {
+			return err
+		}
+	}
+
+	// Validate image rules。
+	for _, rule := range s.ImageRules {
+		if rule.Image == "" {
+			return errors.New("image rule: image is required")
+		}
+		if err := validatePatterns([]string{rule.Image}); err != nil {
+			return fmt.Errorf("image rule: %w", err)
+		}
+		if err := rule.validate(s, fmt.Sprintf("image rule '%s': ", rule.Image)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateProfiles validates the profiles and how they are selected。
+func (s *Settings) validateProfiles() error {
+	profileNames := make([]string, 0, len(s.Profiles))
+	for name := range s.Profiles {
+		profileNames = append(profileNames, name)
+	}
+	sort.Strings(profileNames)
+	for _, name := range profileNames {
+		if name == "" {
+			return errors.New("profiles: profile name must not be empty")
+		}
+		if err := s.Profiles[name].validate(s, fmt.Sprintf("profile '%s': ", name)); err != nil {
+			return err
+		}
+	}
+	if s.ProfileLabel != "" && len(s.Profiles) == 0 {
+		return errors.New("profile_label requires profiles")
+	}
+	if _, found := s.Profiles[s.DefaultProfile]; s.DefaultProfile != "" && !found {
+		return fmt.Errorf("default_profile '%s' is not defined in profiles", s.DefaultProfile)
+	}
+	return nil
+}
+
+// validateMutation validates the probe templates of the mutating mode。
+func (s *Settings) validateMutation() error {
+	for _, probeType := range probeTypes {
+		if _, err := s.Mutation.renderProbe(probeType, "container", templateValidationPort); err != nil {
+			return fmt.Errorf("mutation: %s probe template: %w", probeType, err)
+		}
+	}
+	return nil
+}
+
+// validateEnforcedOperations validates the admission operations the policy enforces。
+func (s *Settings) validateEnforcedOperations() error {
+	// An empty list would accept every request。
+	if s.EnforcedOperations != nil && len(s.EnforcedOperations) == 0 {
+		return fmt.Errorf("enforced_operations: at least one operation must be listed, must be one of: %s",
+			strings.Join(admissionOperations, ", "))
+	}
+	for _, operation := range s.EnforcedOperations {
+		if !containsString(admissionOperations, operation) {
+			return fmt.Errorf("enforced_operations: unknown operation '%s', must be one of: %s",
+				operation, strings.Join(admissionOperations, ", "))
+		}
+	}
+	return nil
+}
+
+// validateExemptions validates the exemption annotations and the exempt requesters。
+func (s *Settings) validateExemptions() error {
+	// Validate the exemption annotations。
+	if s.AllowExemptionAnnotations && s.MaxExemptionDays <= 0 {
+		return errors.New("max_exemption_days must be positive when allow_exemption_annotations is enabled")
+	}
+
+	// Validate the exempt requesters。
+	if err := validatePatterns(s.ExemptUsers); err != nil {
+		return fmt.Errorf("exempt_users: %w", err)
+	}
+	if err := validatePatterns(s.ExemptGroups); err != nil {
+		return fmt.Errorf("exempt_groups: %w", err)
+	}
+	if err := validatePatterns(s.ExemptServiceAccounts); err != nil {
+		return fmt.Errorf("exempt_service_accounts: %w", err)
+	}
+	return nil
+}
+
+// validateNamespaces validates the namespace selection and the namespace overlays。
+func (s *Settings) validateNamespaces() error {
+	if err := validatePatterns(s.ExcludedNamespaces); err != nil {
+		return fmt.Errorf("excluded_namespaces: %w", err)
+	}
+	if err := validatePatterns(s.IncludedNamespaces); err != nil {
+		return fmt.Errorf("included_namespaces: %w", err)
+	}
+	for _, overlay := range s.NamespaceOverlays {
+		if len(overlay.Namespaces) == 0 {
+			return errors.New("namespace overlay: namespaces is required")
+		}
+		if err := validatePatterns(overlay.Namespaces); err != nil {
+			return fmt.Errorf("namespace overlay: %w", err)
+		}
+		prefix := fmt.Sprintf("namespace overlay '%s': ", strings.Join(overlay.Namespaces, ", "))
+		if err := overlay.validate(s, prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateProbeSecurity validates the HTTP and exec probe security configurations。
+func (s *Settings) validateProbeSecurity() error {
+	// Validate HTTP probe security configuration。
+	if err := validatePatterns(s.HTTPProbeSecurity.AllowedHosts); err != nil {
+		return fmt.Errorf("http probe security: allowed_hosts: %w", err)
+	}
+
+	// Validate exec probe configuration。
+	if err := validatePatterns(s.ExecProbe.AllowedCommands); err != nil {
+		return fmt.Errorf("exec probe: allowed_commands: %w", err)
+	}
+	if s.ExecProbe.MaxCommandLength < 0 {
+		return errors.New("exec probe: max_command_length must be non-negative")
+	}
 	return nil
 }
 
 // validateProbeConfig validates individual probe configuration。
-func (s *Settings) validateProbeConfig(probeName string, config ProbeConfig) error {
-	if config.MinPeriodSeconds < 0 {
-		return fmt.Errorf("%s: min_period_seconds must be non-negative", probeName)
+func (s *Settings) validateProbeConfig(probeType, probeName string, config ProbeConfig) error {
+	bounds := []struct {
+		minName, maxName   string
+		minValue, maxValue int32
+	}{
+		{"min_period_seconds", "max_period_seconds", config.MinPeriodSeconds, config.MaxPeriodSeconds},
+		{"min_timeout_seconds", "max_timeout_seconds", config.MinTimeoutSeconds, config.MaxTimeoutSeconds},
+		{"min_initial_delay_seconds", "max_initial_delay_seconds",
+			config.MinInitialDelaySeconds, config.MaxInitialDelaySeconds},
+		{"min_failure_threshold", "max_failure_threshold", config.MinFailureThreshold, config.MaxFailureThreshold},
+		{"min_success_threshold", "max_success_threshold", config.MinSuccessThreshold, config.MaxSuccessThreshold},
+		{"min_termination_grace_period_seconds", "max_termination_grace_period_seconds",
+			config.MinTerminationGracePeriodSeconds, config.MaxTerminationGracePeriodSeconds},
+	}
+
+	for _, bound := range bounds {
+		if bound.minValue < 0 {
+			return fmt.Errorf("%s: %s must be non-negative", probeName, bound.minName)
+		}
+		if bound.maxValue < 0 {
+			return fmt.Errorf("%s: %s must be non-negative", probeName, bound.maxName)
+		}
+		if bound.minValue > 0 && bound.maxValue > 0 && bound.minValue > bound.maxValue {
+			return fmt.Errorf("%s: %s must be less than or equal to %s", probeName, bound.minName, bound.maxName)
+		}
+	}
+
+	if config.MaxDetectionSeconds < 0 {
+		return fmt.Errorf("%s: max_detection_seconds must be non-negative", probeName)
+	}
+
+	if config.MaxTimeoutToPeriodRatio < 0 {
+		return fmt.Errorf("%s: max_timeout_to_period_ratio must be non-negative", probeName)
+	}
+
+	if config.RequiredWhenReplicasAtLeast < 0 {
+		return fmt.Errorf("%s: required_when_replicas_at_least must be non-negative", probeName)
 	}
-	if config.MaxTimeoutSeconds < 0 {
-		return fmt.Errorf("%s: max_timeout_seconds must be non-negative", probeName)
+
+	for _, handler := range config.AllowedHandlers {
+		if !containsString(probeHandlers, handler) {
+			return fmt.Errorf("%s: unknown handler '%s' in allowed_handlers, must be one of: %s",
+				probeName, handler, strings.Join(probeHandlers, ", "))
+		}
+	}
+	for _, handler := range config.ForbiddenHandlers {
+		if !containsString(probeHandlers, handler) {
+			return fmt.Errorf("%s: unknown handler '%s' in forbidden_handlers, must be one of: %s",
+				probeName, handler, strings.Join(probeHandlers, ", "))
+		}
+		if containsString(config.AllowedHandlers, handler) {
+			return fmt.Errorf("%s: handler '%s' is both allowed and forbidden", probeName, handler)
+		}
+	}
+
+	if err := validatePatterns(config.AllowedPaths); err != nil {
+		return fmt.Errorf("%s: allowed_paths: %w", probeName, err)
+	}
+	if err := validatePatterns(config.ForbiddenPaths); err != nil {
+		return fmt.Errorf("%s: forbidden_paths: %w", probeName, err)
+	}
+
+	for _, section := range []struct {
+		name   string
+		config *ProbeConfig
+	}{{"http", config.HTTP}, {"tcp", config.TCP}, {"exec", config.Exec}, {"grpc", config.GRPC}} {
+		if section.config == nil {
+			continue
+		}
+		if !reflect.DeepEqual(ProbeConfig{}.withTimingBounds(*section.config), *section.config) {
+			return fmt.Errorf("%s: %s: only timing bounds can be overridden per handler", probeName, section.name)
+		}
+		merged := config.withTimingBounds(*section.config)
+		merged.HTTP, merged.TCP, merged.Exec, merged.GRPC = nil, nil, nil, nil
+		if err := s.validateProbeConfig(probeType, probeName+" ("+section.name+")", merged); err != nil {
+			return err
+		}
+	}
+
+	if probeType == "readiness" &&
+		(config.MinTerminationGracePeriodSeconds > 0 || config.MaxTerminationGracePeriodSeconds > 0) {
+		return fmt.Errorf("%s: termination grace period bounds are not supported for readiness probes", probeName)
 	}
 	if config.MinPeriodSeconds > 0 && config.MaxTimeoutSeconds > 0 &&
 		config.MinPeriodSeconds <= config.MaxTimeoutSeconds {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 )
 
@@ -256,3 +257,600 @@ func TestValidateProbeTimeSettings(t *testing.T) {
 		})
 	}
 }
+
+func TestParsingRequireExplicitTimings(t *testing.T) {
+	rawSettings := []byte(`{"readiness_probe": {"required": true, "require_explicit_timings": true}}`)
+	settings := Settings{}
+	if err := json.Unmarshal(rawSettings, &settings); err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+
+	if !settings.ReadinessProbe.RequireExplicitTimings {
+		t.Error("Expected ReadinessProbe.RequireExplicitTimings to be true")
+	}
+	if settings.LivenessProbe.RequireExplicitTimings {
+		t.Error("Expected LivenessProbe.RequireExplicitTimings to be false")
+	}
+}
+
+func TestValidateProbeBoundsSettings(t *testing.T) {
+	tests := []struct {
+		name          string
+		settings      Settings
+		expectedError string
+	}{
+		{
+			name: "consistent bounds",
+			settings: Settings{
+				LivenessProbe: ProbeConfig{
+					MinPeriodSeconds: 10, MaxPeriodSeconds: 60,
+					MinTimeoutSeconds: 1, MaxTimeoutSeconds: 5,
+					MinInitialDelaySeconds: 0, MaxInitialDelaySeconds: 120,
+					MinFailureThreshold: 3, MaxFailureThreshold: 10,
+					MinSuccessThreshold: 1, MaxSuccessThreshold: 1,
+					MinTerminationGracePeriodSeconds: 10, MaxTerminationGracePeriodSeconds: 60,
+				},
+			},
+		},
+		{
+			name:          "period bounds inverted",
+			settings:      Settings{ReadinessProbe: ProbeConfig{MinPeriodSeconds: 60, MaxPeriodSeconds: 10}},
+			expectedError: "readiness probe: min_period_seconds must be less than or equal to max_period_seconds",
+		},
+		{
+			name:          "failure threshold bounds inverted",
+			settings:      Settings{StartupProbe: ProbeConfig{MinFailureThreshold: 10, MaxFailureThreshold: 3}},
+			expectedError: "startup probe: min_failure_threshold must be less than or equal to max_failure_threshold",
+		},
+		{
+			name:          "negative initial delay",
+			settings:      Settings{LivenessProbe: ProbeConfig{MaxInitialDelaySeconds: -1}},
+			expectedError: "liveness probe: max_initial_delay_seconds must be non-negative",
+		},
+		{
+			name:          "termination grace period on readiness",
+			settings:      Settings{ReadinessProbe: ProbeConfig{MaxTerminationGracePeriodSeconds: 30}},
+			expectedError: "readiness probe: termination grace period bounds are not supported for readiness probes",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.settings.Validate()
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Expected settings to be valid, got error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("Expected settings to be invalid with %q", test.expectedError)
+			}
+			if err.Error() != test.expectedError {
+				t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestValidateProbeHandlerSettings(t *testing.T) {
+	tests := []struct {
+		name          string
+		settings      Settings
+		expectedError string
+	}{
+		{
+			name: "known handlers",
+			settings: Settings{
+				LivenessProbe:  ProbeConfig{ForbiddenHandlers: []string{"exec"}},
+				ReadinessProbe: ProbeConfig{AllowedHandlers: []string{"httpGet", "grpc"}},
+			},
+		},
+		{
+			name:     "unknown allowed handler",
+			settings: Settings{ReadinessProbe: ProbeConfig{AllowedHandlers: []string{"http"}}},
+			expectedError: "readiness probe: unknown handler 'http' in allowed_handlers, " +
+				"must be one of: httpGet, tcpSocket, exec, grpc",
+		},
+		{
+			name:     "unknown forbidden handler",
+			settings: Settings{LivenessProbe: ProbeConfig{ForbiddenHandlers: []string{"shell"}}},
+			expectedError: "liveness probe: unknown handler 'shell' in forbidden_handlers, " +
+				"must be one of: httpGet, tcpSocket, exec, grpc",
+		},
+		{
+			name: "handler both allowed and forbidden",
+			settings: Settings{LivenessProbe: ProbeConfig{
+				AllowedHandlers:   []string{"exec", "httpGet"},
+				ForbiddenHandlers: []string{"exec"},
+			}},
+			expectedError: "liveness probe: handler 'exec' is both allowed and forbidden",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.settings.Validate()
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Expected settings to be valid, got error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("Expected settings to be invalid with %q", test.expectedError)
+			}
+			if err.Error() != test.expectedError {
+				t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestValidatePathPatternSettings(t *testing.T) {
+	valid := Settings{LivenessProbe: ProbeConfig{
+		AllowedPaths:   []string{"/live*"},
+		ForbiddenPaths: []string{"regex:^/health/(db|cache)"},
+	}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected settings to be valid, got error: %v", err)
+	}
+
+	invalid := Settings{ReadinessProbe: ProbeConfig{ForbiddenPaths: []string{"regex:(unclosed"}}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("Expected settings with invalid regular expression to be rejected")
+	}
+}
+
+func TestValidateExecProbeSettings(t *testing.T) {
+	valid := Settings{ExecProbe: ExecProbeConfig{
+		ForbidShellWrappers: true,
+		AllowedCommands:     []string{"grpc_health_probe", "regex:^/usr/bin/pg_"},
+		MaxCommandLength:    128,
+	}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected settings to be valid, got error: %v", err)
+	}
+
+	negative := Settings{ExecProbe: ExecProbeConfig{MaxCommandLength: -1}}
+	if err := negative.Validate(); err == nil {
+		t.Error("Expected negative max_command_length to be rejected")
+	}
+
+	invalidPattern := Settings{ExecProbe: ExecProbeConfig{AllowedCommands: []string{"regex:(pg"}}}
+	if err := invalidPattern.Validate(); err == nil {
+		t.Error("Expected invalid allowed_commands pattern to be rejected")
+	}
+}
+
+func TestValidateMaxStartupSecondsSettings(t *testing.T) {
+	if err := (&Settings{MaxStartupSeconds: 300}).Validate(); err != nil {
+		t.Errorf("Expected settings to be valid, got error: %v", err)
+	}
+	if err := (&Settings{MaxStartupSeconds: -1}).Validate(); err == nil {
+		t.Error("Expected negative max_startup_seconds to be rejected")
+	}
+}
+
+func TestValidateTimeoutToPeriodRatioSettings(t *testing.T) {
+	if err := (&Settings{LivenessProbe: ProbeConfig{MaxTimeoutToPeriodRatio: 0.5}}).Validate(); err != nil {
+		t.Errorf("Expected settings to be valid, got error: %v", err)
+	}
+	if err := (&Settings{LivenessProbe: ProbeConfig{MaxTimeoutToPeriodRatio: -0.5}}).Validate(); err == nil {
+		t.Error("Expected negative max_timeout_to_period_ratio to be rejected")
+	}
+}
+
+func TestValidateHandlerOverrideSettings(t *testing.T) {
+	tests := []struct {
+		name          string
+		settings      string
+		expectedError string
+	}{
+		{
+			name: "timing bounds per handler",
+			settings: `{"liveness_probe": {"max_timeout_seconds": 2,
+				"exec": {"max_timeout_seconds": 10}, "grpc": {"min_period_seconds": 5}}}`,
+		},
+		{
+			name:          "non timing field in handler section",
+			settings:      `{"liveness_probe": {"http": {"required": true}}}`,
+			expectedError: "liveness probe: http: only timing bounds can be overridden per handler",
+		},
+		{
+			name:     "inconsistent bounds after merge",
+			settings: `{"readiness_probe": {"max_period_seconds": 30, "tcp": {"min_period_seconds": 60}}}`,
+			expectedError: "readiness probe (tcp): min_period_seconds must be less than or equal to " +
+				"max_period_seconds",
+		},
+		{
+			name:     "termination grace period on readiness handler section",
+			settings: `{"readiness_probe": {"exec": {"max_termination_grace_period_seconds": 30}}}`,
+			expectedError: "readiness probe (exec): termination grace period bounds are not supported " +
+				"for readiness probes",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := Settings{}
+			if err := json.Unmarshal([]byte(test.settings), &settings); err != nil {
+				t.Fatalf("Unexpected error: %+v", err)
+			}
+
+			err := settings.Validate()
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Expected settings to be valid, got error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("Expected settings to be invalid with %q", test.expectedError)
+			}
+			if err.Error() != test.expectedError {
+				t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestValidateContainerOverrideSettings(t *testing.T) {
+	tests := []struct {
+		name          string
+		settings      string
+		expectedError string
+	}{
+		{
+			name: "valid overrides",
+			settings: `{"container_overrides": [
+				{"name": "istio-proxy", "exempt": true},
+				{"name": "regex:^fluent-.*$", "liveness_probe": {"required": false, "max_timeout_seconds": 5}}
+			]}`,
+		},
+		{
+			name:          "missing name",
+			settings:      `{"container_overrides": [{"exempt": true}]}`,
+			expectedError: "container override: name is required",
+		},
+		{
+			name:     "invalid name pattern",
+			settings: `{"container_overrides": [{"name": "regex:(proxy", "exempt": true}]}`,
+			expectedError: "container override: invalid pattern 'regex:(proxy': " +
+				"error parsing regexp: missing closing ): `(proxy`",
+		},
+		{
+			name: "invalid probe configuration",
+			settings: `{"container_overrides": [
+				{"name": "sidecar", "readiness_probe": {"min_period_seconds": -1}}
+			]}`,
+			expectedError: "container override 'sidecar': readiness probe: min_period_seconds must be non-negative",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := Settings{}
+			if err := json.Unmarshal([]byte(test.settings), &settings); err != nil {
+				t.Fatalf("Unexpected error: %+v", err)
+			}
+
+			err := settings.Validate()
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Expected settings to be valid, got error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("Expected settings to be invalid with %q", test.expectedError)
+			}
+			if err.Error() != test.expectedError {
+				t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestImageRepository(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                                "nginx",
+		"nginx:1.25":                           "nginx",
+		"docker.io/library/openjdk:17-jdk":     "docker.io/library/openjdk",
+		"registry.example.com:5000/vendor/app": "registry.example.com:5000/vendor/app",
+		"registry.example.com:5000/app:2.0":    "registry.example.com:5000/app",
+		"ghcr.io/acme/app@sha256:0123abcd":     "ghcr.io/acme/app",
+		"ghcr.io/acme/app:1.0@sha256:0123abcd": "ghcr.io/acme/app",
+	}
+
+	for image, expected := range tests {
+		if repository := imageRepository(image); repository != expected {
+			t.Errorf("Expected repository of %q to be %q, got %q", image, expected, repository)
+		}
+	}
+}
+
+func TestValidateImageRuleSettings(t *testing.T) {
+	valid := Settings{}
+	if err := json.Unmarshal([]byte(`{"image_rules": [
+		{"image": "*/openjdk*", "ignore_tag": true, "startup_probe": {"required": true}},
+		{"image": "regex:^registry\\.example\\.com/vendor/", "exempt": true}
+	]}`), &valid); err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected settings to be valid, got error: %v", err)
+	}
+
+	missingImage := Settings{ImageRules: []ImageRule{{Exempt: true}}}
+	if err := missingImage.Validate(); err == nil || err.Error() != "image rule: image is required" {
+		t.Errorf("Expected missing image to be rejected, got: %v", err)
+	}
+
+	invalidProbe := Settings{ImageRules: []ImageRule{{
+		Image:          "*spring*",
+		ProbeOverrides: ProbeOverrides{StartupProbe: &ProbeConfig{MinPeriodSeconds: -1}},
+	}}}
+	expected := "image rule '*spring*': startup probe: min_period_seconds must be non-negative"
+	if err := invalidProbe.Validate(); err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, got: %v", expected, err)
+	}
+}
+
+func TestValidateNamespaceSettings(t *testing.T) {
+	tests := []struct {
+		name          string
+		settings      string
+		expectedError string
+	}{
+		{
+			name: "valid namespace settings",
+			settings: `{"excluded_namespaces": ["kube-*"], "included_namespaces": ["regex:^team-"],
+				"namespace_overlays": [{"namespaces": ["monitoring"], "readiness_probe": {"required": false}}]}`,
+		},
+		{
+			name:     "invalid excluded namespace pattern",
+			settings: `{"excluded_namespaces": ["regex:[kube"]}`,
+			expectedError: "excluded_namespaces: invalid pattern 'regex:[kube': " +
+				"error parsing regexp: missing closing ]: `[kube`",
+		},
+		{
+			name:          "overlay without namespaces",
+			settings:      `{"namespace_overlays": [{"readiness_probe": {"required": false}}]}`,
+			expectedError: "namespace overlay: namespaces is required",
+		},
+		{
+			name: "invalid overlay probe configuration",
+			settings: `{"namespace_overlays": [
+				{"namespaces": ["batch-*"], "liveness_probe": {"max_timeout_seconds": -1}}
+			]}`,
+			expectedError: "namespace overlay 'batch-*': liveness probe: max_timeout_seconds must be non-negative",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := Settings{}
+			if err := json.Unmarshal([]byte(test.settings), &settings); err != nil {
+				t.Fatalf("Unexpected error: %+v", err)
+			}
+
+			err := settings.Validate()
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Expected settings to be valid, got error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("Expected settings to be invalid with %q", test.expectedError)
+			}
+			if err.Error() != test.expectedError {
+				t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestValidateExemptRequesterSettings(t *testing.T) {
+	valid := Settings{
+		ExemptUsers:           []string{"admin@example.com"},
+		ExemptGroups:          []string{"regex:^break-glass-"},
+		ExemptServiceAccounts: []string{"argocd:*"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected settings to be valid, got error: %v", err)
+	}
+
+	invalid := Settings{ExemptServiceAccounts: []string{"regex:argocd:("}}
+	expected := "exempt_service_accounts: invalid pattern 'regex:argocd:(': " +
+		"error parsing regexp: missing closing ): `argocd:(`"
+	if err := invalid.Validate(); err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, got: %v", expected, err)
+	}
+}
+
+func TestValidateExemptionAnnotationSettings(t *testing.T) {
+	settings := DefaultSettings()
+	if settings.AllowExemptionAnnotations {
+		t.Error("Expected exemption annotations to be disabled by default")
+	}
+	if settings.MaxExemptionDays != defaultMaxExemptionDays {
+		t.Errorf("Expected exemptions to last at most %d days by default, got %d",
+			defaultMaxExemptionDays, settings.MaxExemptionDays)
+	}
+
+	settings.AllowExemptionAnnotations = true
+	settings.MaxExemptionDays = 0
+	expected := "max_exemption_days must be positive when allow_exemption_annotations is enabled"
+	if err := settings.Validate(); err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, got: %v", expected, err)
+	}
+}
+
+func TestValidateProfileSettings(t *testing.T) {
+	tests := []struct {
+		name          string
+		settings      string
+		expectedError string
+	}{
+		{
+			name: "valid profiles",
+			settings: `{"profile_label": "workload-class", "default_profile": "web", "profiles": {
+				"web": {"readiness_probe": {"required": true}},
+				"worker": {"readiness_probe": {"required": false}, "liveness_probe": {"required": true}}
+			}}`,
+		},
+		{
+			name:          "unknown default profile",
+			settings:      `{"default_profile": "web", "profiles": {"worker": {}}}`,
+			expectedError: "default_profile 'web' is not defined in profiles",
+		},
+		{
+			name:          "profile label without profiles",
+			settings:      `{"profile_label": "workload-class"}`,
+			expectedError: "profile_label requires profiles",
+		},
+		{
+			name:          "negative replica threshold in a profile",
+			settings:      `{"profiles": {"web": {"readiness_probe": {"required_when_replicas_at_least": -1}}}}`,
+			expectedError: "profile 'web': readiness probe: required_when_replicas_at_least must be non-negative",
+		},
+		{
+			name:          "invalid profile probe configuration",
+			settings:      `{"profiles": {"batch": {"startup_probe": {"min_period_seconds": -1}}}}`,
+			expectedError: "profile 'batch': startup probe: min_period_seconds must be non-negative",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := Settings{}
+			if err := json.Unmarshal([]byte(test.settings), &settings); err != nil {
+				t.Fatalf("Unexpected error: %+v", err)
+			}
+
+			err := settings.Validate()
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Expected settings to be valid, got error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != test.expectedError {
+				t.Errorf("Expected error %q, got: %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateEnforcedOperationsSettings(t *testing.T) {
+	settings := DefaultSettings()
+	if err := settings.Validate(); err != nil {
+		t.Errorf("Expected default settings to be valid, got error: %v", err)
+	}
+	if !reflect.DeepEqual(settings.EnforcedOperations, []string{"CREATE", "UPDATE"}) {
+		t.Errorf("Expected CREATE and UPDATE to be enforced by default, got %v", settings.EnforcedOperations)
+	}
+
+	tests := []struct {
+		name          string
+		settings      string
+		expectedError string
+	}{
+		{
+			name:     "selected operations",
+			settings: `{"enforced_operations": ["CREATE"]}`,
+		},
+		{
+			name:          "unknown operation",
+			settings:      `{"enforced_operations": ["CREATE", "PATCH"]}`,
+			expectedError: "enforced_operations: unknown operation 'PATCH', must be one of: CREATE, UPDATE",
+		},
+		{
+			name:          "delete is never enforced",
+			settings:      `{"enforced_operations": ["DELETE"]}`,
+			expectedError: "enforced_operations: unknown operation 'DELETE', must be one of: CREATE, UPDATE",
+		},
+		{
+			name:     "null keeps the default",
+			settings: `{"enforced_operations": null}`,
+		},
+		{
+			name:     "empty list",
+			settings: `{"enforced_operations": []}`,
+			expectedError: "enforced_operations: at least one operation must be listed, " +
+				"must be one of: CREATE, UPDATE",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := DefaultSettings()
+			if err := json.Unmarshal([]byte(test.settings), settings); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if settings.EnforcedOperations == nil {
+				t.Fatal("Expected enforced operations to be set")
+			}
+			err := settings.Validate()
+			if test.expectedError == "" && err != nil {
+				t.Errorf("Expected settings to be valid, got error: %v", err)
+			}
+			if test.expectedError != "" && (err == nil || err.Error() != test.expectedError) {
+				t.Errorf("Expected error %q, got: %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateMutationSettings(t *testing.T) {
+	tests := []struct {
+		name          string
+		settings      string
+		expectedError string
+	}{
+		{
+			name: "valid templates",
+			settings: `{"mutation": {"enabled": true,
+				"readiness_probe": {"httpGet": {"path": "/{{container}}/ready", "port": "{{port}}"}}}}`,
+		},
+		{
+			name:          "template without handler",
+			settings:      `{"mutation": {"liveness_probe": {"periodSeconds": 10}}}`,
+			expectedError: "mutation: liveness probe template: no handler defined",
+		},
+		{
+			name:          "template with several handlers",
+			settings:      `{"mutation": {"startup_probe": {"tcpSocket": {"port": "{{port}}"}, "exec": {"command": ["true"]}}}}`,
+			expectedError: "mutation: startup probe template: several handlers defined (tcpSocket, exec)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := Settings{}
+			if err := json.Unmarshal([]byte(test.settings), &settings); err != nil {
+				t.Fatalf("Unexpected error: %+v", err)
+			}
+
+			err := settings.Validate()
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Expected settings to be valid, got error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != test.expectedError {
+				t.Errorf("Expected error %q, got: %v", test.expectedError, err)
+			}
+		})
+	}
+}
@@ -5,12 +5,86 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"path"
+	"reflect"
+	"strings"
 
 	kubewarden "github.com/kubewarden/policy-sdk-go"
 	kubewarden_protocol "github.com/kubewarden/policy-sdk-go/protocol"
 	"github.com/tidwall/gjson"
 )
 
+// Defaults the kubelet applies to unset probe fields。
+const (
+	defaultInitialDelaySeconds = 0
+	defaultPeriodSeconds       = 10
+	defaultTimeoutSeconds      = 1
+	defaultSuccessThreshold    = 1
+	defaultFailureThreshold    = 3
+
+	defaultTerminationGracePeriodSeconds = 30
+)
+
+// explicitTimingFields lists the probe fields require_explicit_timings expects to be set。
+//
+//nolint:gochecknoglobals // Read-only lookup table.
+var explicitTimingFields = []struct {
+	name         string
+	defaultValue int64
+}{
+	{"initialDelaySeconds", defaultInitialDelaySeconds},
+	{"periodSeconds", defaultPeriodSeconds},
+	{"timeoutSeconds", defaultTimeoutSeconds},
+	{"successThreshold", defaultSuccessThreshold},
+	{"failureThreshold", defaultFailureThreshold},
+}
+
+// probeTypes lists the probe types of a container。The probe field is the type followed by "Probe"。
+//
+//nolint:gochecknoglobals // Read-only lookup table.
+var probeTypes = []string{"liveness", "readiness", "startup"}
+
+// probeHandlers lists the handlers a probe can define, as named in the probe spec。
+//
+//nolint:gochecknoglobals // Read-only lookup table.
+var probeHandlers = []string{"httpGet", "tcpSocket", "exec", "grpc"}
+
+// shells lists the binaries considered shell wrappers when they run an inline script。
+//
+//nolint:gochecknoglobals // Read-only lookup table.
+var shells = []string{"sh", "bash", "ash", "dash", "zsh", "ksh"}
+
+// shellOptionsWithArgument lists the shell options whose argument is a separate command argument。
+//
+//nolint:gochecknoglobals // Read-only lookup table.
+var shellOptionsWithArgument = []string{"-o", "+o", "-O", "+O", "--rcfile", "--init-file"}
+
+// envOptionsWithArgument lists the env options whose argument is a separate command argument。
+//
+//nolint:gochecknoglobals // Read-only lookup table.
+var envOptionsWithArgument = []string{"-u", "--unset", "-C", "--chdir", "-S", "--split-string"}
+
+// probeTimings holds the effective timing values of a probe。
+type probeTimings struct {
+	InitialDelaySeconds int64
+	PeriodSeconds       int64
+	TimeoutSeconds      int64
+	SuccessThreshold    int64
+	FailureThreshold    int64
+
+	TerminationGracePeriodSeconds int64
+}
+
+// failureWindow returns how long, in seconds, the probe keeps failing before the kubelet acts on it。
+func (t probeTimings) failureWindow() int64 {
+	return t.PeriodSeconds * t.FailureThreshold
+}
+
+// detectionTime returns the worst-case time, in seconds, for the probe to detect a broken container。
+func (t probeTimings) detectionTime() int64 {
+	return t.failureWindow() + t.TimeoutSeconds
+}
+
 // validate validates the deployment configuration。
 func validate(payload []byte) ([]byte, error) {
 	// Parse the validation request。
@@ -36,13 +110,39 @@ func validate(payload []byte) ([]byte, error) {
 			kubewarden.Code(http.StatusBadRequest))
 	}
 
-	// Validate deployment。
-	if validateErr := validateDeployment(validationRequest.Request.Object, settings); validateErr != nil {
+	// Skip the requests the policy does not enforce, then resolve the settings and the exemptions。
+	if skippedRequest(validationRequest.Request, settings) {
+		return kubewarden.AcceptRequest()
+	}
+	scope, scopeErr := newRequestScope(validationRequest.Request, settings)
+	if scopeErr != nil {
+		return kubewarden.RejectRequest(
+			kubewarden.Message(scopeErr.Error()),
+			kubewarden.Code(http.StatusBadRequest))
+	}
+	kind, settings, exempted := scope.kind, scope.settings, scope.exempted
+
+	// Validate deployment, injecting the missing probes in mutating mode。
+	found, validateErr := validateObject(validationRequest.Request, kind, settings, exempted)
+	if validateErr == nil && settings.Mutation.Enabled && len(found.missingProbes()) > 0 {
+		if response, mutated := mutateMissingProbes(validationRequest, kind, settings, exempted, found); mutated {
+			return response, nil
+		}
+	}
+	if validateErr == nil && !found.empty() {
+		validateErr = found
+	}
+	if validateErr != nil {
 		logger.WarnWith("deployment validation failed").
 			Err("error", validateErr).
+			Bool("dry_run", validationRequest.Request.DryRun).
 			Write()
+		message := validateErr.Error()
+		if validationRequest.Request.DryRun && settings.DryRunFullMessage && found != nil {
+			message = found.message(0)
+		}
 		return kubewarden.RejectRequest(
-			kubewarden.Message(validateErr.Error()),
+			kubewarden.Message(message),
 			kubewarden.Code(http.StatusBadRequest))
 	}
 
@@ -50,131 +150,829 @@ func validate(payload []byte) ([]byte, error) {
 	return kubewarden.AcceptRequest()
 }
 
-// validateDeployment validates the deployment configuration。
-func validateDeployment(deploymentJSON []byte, settings Settings) error {
+// requestScope holds the kind of the requested object, the settings applying to it and the probe
+// types its annotations exempt。
+type requestScope struct {
+	kind     string
+	settings Settings
+	exempted []string
+}
+
+// skippedRequest reports whether the policy lets the request through without validating it: the
+// subresources, such as scale and status, the operations the policy does not enforce, the exempt
+// requesters and the namespaces the policy does not enforce are skipped。A request without an
+// operation is validated。
+func skippedRequest(request kubewarden_protocol.KubernetesAdmissionRequest, settings Settings) bool {
+	if request.SubResource != "" {
+		logger.InfoWith("subresource request, deployment validation skipped").
+			String("subresource", request.SubResource).
+			Write()
+		return true
+	}
+	if request.Operation != "" && !containsString(settings.EnforcedOperations, request.Operation) {
+		logger.InfoWith("operation not enforced, deployment validation skipped").
+			String("operation", request.Operation).
+			Write()
+		return true
+	}
+
+	// Let the exempt users, groups and service accounts bypass the policy。
+	if exemptedBy, exempt := settings.exemptRequester(request.UserInfo); exempt {
+		logger.InfoWith("requesting user exempt, deployment validation skipped").
+			String("user", request.UserInfo.Username).
+			String("groups", strings.Join(request.UserInfo.Groups, ", ")).
+			String("uid", request.Uid).
+			String("exempted_by", exemptedBy).
+			Write()
+		return true
+	}
+
+	if namespace := requestNamespace(request); !settings.enforcesNamespace(namespace) {
+		logger.InfoWith("namespace not enforced, deployment validation skipped").
+			String("namespace", namespace).
+			Write()
+		return true
+	}
+	return false
+}
+
+// requestNamespace returns the namespace of the request or, when the request has none, of the object。
+func requestNamespace(request kubewarden_protocol.KubernetesAdmissionRequest) string {
+	if request.Namespace != "" {
+		return request.Namespace
+	}
+	return gjson.GetBytes(request.Object, "metadata.namespace").String()
+}
+
+// newRequestScope applies the namespace overlay to the settings and reads the exemption annotations
+// of the object。The error rejects the request when its kind has no pod spec or its exemption
+// annotations are invalid。
+func newRequestScope(request kubewarden_protocol.KubernetesAdmissionRequest, settings Settings) (requestScope, error) {
+	namespace := requestNamespace(request)
+	if overlay := settings.namespaceOverlay(namespace); overlay != nil {
+		logger.InfoWith("namespace overlay applied").
+			String("namespace", namespace).
+			String("overlay", strings.Join(overlay.Namespaces, ", ")).
+			Write()
+		settings = overlay.apply(settings)
+		// The overlay takes precedence over the profile validateDeployment applies。
+		settings.Profiles = overlay.overProfiles(settings.Profiles)
+	}
+
+	kind := request.Kind.Kind
+	if kind == "" {
+		kind = gjson.GetBytes(request.Object, "kind").String()
+	}
+
+	// Reject the kinds without a pod spec before reading their annotations。
+	if _, err := podSpecPath(kind); err != nil {
+		logger.WarnWith("deployment validation failed").
+			Err("error", err).
+			Write()
+		return requestScope{}, err
+	}
+
+	// Honor the exemption annotations。
+	scope := requestScope{kind: kind, settings: settings, exempted: []string{}}
+	if !settings.AllowExemptionAnnotations {
+		return scope, nil
+	}
+	exemption, exempt, err := annotationExemption(kind, request.Object, settings.MaxExemptionDays)
+	if err != nil {
+		logger.WarnWith("invalid probe exemption").
+			Err("error", err).
+			Write()
+		return requestScope{}, fmt.Errorf("invalid probe exemption: %w", err)
+	}
+	if exempt {
+		logger.InfoWith("probe exemption applied").
+			String("probes", strings.Join(exemption.probes, ", ")).
+			String("reason", exemption.reason).
+			String("expires", exemption.expires).
+			Write()
+		scope.exempted = exemption.probes
+	}
+	return scope, nil
+}
+
+// validateObject validates the object of the request and returns the violations left once the
+// grandfathered and the exempted ones are dropped and, on UPDATE, the probe regressions added。A
+// malformed object is reported as an error。
+func validateObject(request kubewarden_protocol.KubernetesAdmissionRequest, kind string, settings Settings,
+	exempted []string) (*violations, error) {
+	found := &violations{}
+	if err := validateDeployment(kind, request.Object, settings); err != nil && !errors.As(err, &found) {
+		return nil, err
+	}
+
+	update := request.Operation == "UPDATE"
+	if update && settings.GrandfatherOnUpdate {
+		found = withoutPreexistingViolations(kind, request.OldObject, settings, found)
+	}
+	if update && settings.NoRegression {
+		validateProbeRegressions(kind, request.Object, request.OldObject, settings, found)
+	}
+	return found.withoutProbes(exempted), nil
+}
+
+// withoutPreexistingViolations drops the violations the old object already had, and logs them。
+func withoutPreexistingViolations(kind string, oldObject []byte, settings Settings, found *violations) *violations {
+	var previous *violations
+	if !errors.As(validateDeployment(kind, oldObject, settings), &previous) {
+		return found
+	}
+
+	introduced, preexisting := found.split(previous)
+	if !preexisting.empty() {
+		logger.InfoWith("pre-existing violations accepted").
+			String("violations", preexisting.message(0)).
+			Write()
+	}
+	return introduced
+}
+
+// podSpecPath returns the path of the pod spec inside an object of the given kind。
+func podSpecPath(kind string) (string, error) {
+	switch kind {
+	case "Pod":
+		return "spec", nil
+	case "CronJob":
+		return "spec.jobTemplate.spec.template.spec", nil
+	case "Deployment", "ReplicaSet", "StatefulSet", "DaemonSet", "Job", "ReplicationController":
+		return "spec.template.spec", nil
+	default:
+		return "", fmt.Errorf("unsupported kind '%s': object should be one of these kinds: "+
+			"Deployment, ReplicaSet, StatefulSet, DaemonSet, ReplicationController, Job, CronJob, Pod", kind)
+	}
+}
+
+// unknownReplicas is the replica count of the workloads whose kind has no replicas or which do not
+// set them, for instance because a HorizontalPodAutoscaler manages them。
+const unknownReplicas = -1
+
+// workloadReplicas returns the replica count the workload asks for, or unknownReplicas。
+func workloadReplicas(kind string, objectJSON []byte) int64 {
+	switch kind {
+	case "Deployment", "ReplicaSet", "StatefulSet", "ReplicationController":
+		if replicas := gjson.GetBytes(objectJSON, "spec.replicas"); replicas.Exists() {
+			return replicas.Int()
+		}
+	}
+	return unknownReplicas
+}
+
+// podTemplateMetadataPath returns the path of the metadata next to the pod spec found at specPath。
+func podTemplateMetadataPath(specPath string) string {
+	return strings.TrimSuffix(specPath, "spec") + "metadata"
+}
+
+// profileLabelValue returns the value of the profile label of the workload or, when the workload
+// does not set it, of its pod template。
+func profileLabelValue(objectJSON []byte, specPath, label string) string {
+	if label == "" {
+		return ""
+	}
+	if value, found := gjson.GetBytes(objectJSON, "metadata.labels").Map()[label]; found {
+		return value.String()
+	}
+	return gjson.GetBytes(objectJSON, podTemplateMetadataPath(specPath)+".labels").Map()[label].String()
+}
+
+// validateDeployment validates the pod spec of a deployment or any other pod controller。
+func validateDeployment(kind string, objectJSON []byte, settings Settings) error {
+	specPath, err := podSpecPath(kind)
+	if err != nil {
+		return err
+	}
+
+	// Apply the profile selected by the workload labels。
+	profileName, profile, err := settings.profile(profileLabelValue(objectJSON, specPath, settings.ProfileLabel))
+	if err != nil {
+		return err
+	}
+	if profile != nil {
+		logger.InfoWith("profile applied").
+			String("profile", profileName).
+			Write()
+		settings = profile.apply(settings)
+	}
+
 	// Validate containers
-	containers := gjson.GetBytes(deploymentJSON, "spec.template.spec.containers")
+	podSpec := gjson.GetBytes(objectJSON, specPath)
+	containers := podSpec.Get("containers")
 	if !containers.Exists() {
-		return errors.New("invalid deployment: missing containers")
+		return fmt.Errorf("invalid %s: missing containers", strings.ToLower(kind))
 	}
 
 	if !containers.IsArray() {
-		return errors.New("invalid deployment: containers must be an array")
+		return fmt.Errorf("invalid %s: containers must be an array", strings.ToLower(kind))
 	}
 
 	if len(containers.Array()) == 0 {
-		return errors.New("no containers found in deployment")
+		return fmt.Errorf("no containers found in %s", strings.ToLower(kind))
 	}
 
-	// Validate each container's probes。
+	// Validate each container's probes, collecting every violation。
+	found := &violations{profile: profileName}
 	var validationErr error
+	replicas := workloadReplicas(kind, objectJSON)
 	containers.ForEach(func(_, container gjson.Result) bool {
-		if err := validateContainer(container, settings); err != nil {
+		if err := validateContainer(container, podSpec, replicas, settings, found); err != nil {
 			validationErr = err
 			return false
 		}
 		return true
 	})
 
-	return validationErr
+	if validationErr != nil {
+		return validationErr
+	}
+	if !found.empty() {
+		return found
+	}
+	return nil
 }
 
-// validateContainer validates a single container's probe configurations。
-func validateContainer(container gjson.Result, settings Settings) error {
+// validateContainer validates a single container's probe configurations, recording the
+// violations in found。
+func validateContainer(container, podSpec gjson.Result, replicas int64, settings Settings,
+	found *violations) error {
 	containerName := container.Get("name").String()
 	if containerName == "" {
 		return errors.New("container name is required")
 	}
 
-	// Validate liveness probe。
-	if err := validateLivenessProbe(container, containerName, settings.LivenessProbe); err != nil {
-		return err
+	// Resolve the requirements of this container。
+	settings, exempt := resolveContainerSettings(containerName, container.Get("image").String(), settings)
+	if exempt {
+		return nil
 	}
 
+	// Relax the requirements depending on the replica count of the workload。
+	settings.LivenessProbe.Required = settings.LivenessProbe.requiredFor(replicas)
+	settings.ReadinessProbe.Required = settings.ReadinessProbe.requiredFor(replicas)
+	settings.StartupProbe.Required = settings.StartupProbe.requiredFor(replicas)
+
+	// Validate liveness probe。
+	validateLivenessProbe(container, podSpec, containerName, settings.LivenessProbe, found)
+
 	// Validate readiness probe。
-	if err := validateReadinessProbe(container, containerName, settings.ReadinessProbe); err != nil {
-		return err
-	}
+	validateReadinessProbe(container, podSpec, containerName, settings.ReadinessProbe, found)
 
 	// Validate startup probe。
-	if err := validateStartupProbe(container, containerName, settings.StartupProbe); err != nil {
-		return err
+	validateStartupProbe(container, podSpec, containerName, settings.StartupProbe, found)
+
+	// Validate the ports targeted by the probes。
+	if settings.ProbePorts.Enabled {
+		validateProbePorts(container, podSpec, containerName, settings.ProbePorts, found)
+	}
+
+	// Validate the targets and headers of the HTTP probes。
+	validateHTTPProbeSecurity(container, containerName, settings.HTTPProbeSecurity, found)
+
+	// Validate the commands of the exec probes。
+	validateExecProbes(container, containerName, settings.ExecProbe, found)
+
+	// Validate the relations between the probes of the container。
+	validateProbeRelations(container, podSpec, containerName, settings.ProbeRelations, found)
+
+	// Validate the time the container is given to start。
+	if settings.MaxStartupSeconds > 0 {
+		validateStartupBudget(container, podSpec, containerName, settings.MaxStartupSeconds, found)
 	}
 
 	return nil
 }
 
+// resolveContainerSettings returns the settings applying to a container, and whether the container
+// is exempt from every probe check。The settings of the request are layered in this order, each
+// layer replacing the probe configurations it sets:
+//  1. the profile selected by the workload labels, applied by validateDeployment;
+//  2. the namespace overlay matching the request namespace, applied by validate to the base
+//     settings and to every profile so that it takes precedence over the profile;
+//  3. every image rule matching the container image, in the order they are listed;
+//  4. the first container override matching the container name。
+//
+// The first two layers are already applied to the given settings。
+func resolveContainerSettings(containerName, image string, settings Settings) (Settings, bool) {
+	resolved := settings
+	for _, rule := range settings.matchingImageRules(image) {
+		logger.InfoWith("image rule applied").
+			String("container", containerName).
+			String("image", image).
+			String("rule", rule.Image).
+			Bool("exempt", rule.Exempt).
+			Write()
+		if rule.Exempt {
+			return resolved, true
+		}
+		resolved = rule.apply(resolved)
+	}
+
+	if override := settings.containerOverride(containerName); override != nil {
+		logger.InfoWith("container override applied").
+			String("container", containerName).
+			String("override", override.Name).
+			Bool("exempt", override.Exempt).
+			Write()
+		if override.Exempt {
+			return resolved, true
+		}
+		resolved = override.apply(resolved)
+	}
+
+	return resolved, false
+}
+
 // validateLivenessProbe validates the liveness probe configuration。
-func validateLivenessProbe(container gjson.Result, containerName string, config ProbeConfig) error {
-	if config.Required && !container.Get("livenessProbe").Exists() {
-		return fmt.Errorf("container '%s': missing liveness probe", containerName)
+func validateLivenessProbe(container, podSpec gjson.Result, containerName string, config ProbeConfig,
+	found *violations) {
+	validateProbe(container.Get("livenessProbe"), podSpec, "liveness", containerName, config, found)
+}
+
+// validateReadinessProbe validates the readiness probe configuration。
+func validateReadinessProbe(container, podSpec gjson.Result, containerName string, config ProbeConfig,
+	found *violations) {
+	validateProbe(container.Get("readinessProbe"), podSpec, "readiness", containerName, config, found)
+}
+
+// validateStartupProbe validates the startup probe configuration。
+func validateStartupProbe(container, podSpec gjson.Result, containerName string, config ProbeConfig,
+	found *violations) {
+	validateProbe(container.Get("startupProbe"), podSpec, "startup", containerName, config, found)
+}
+
+// validateProbe validates a single probe of a container against its configuration。
+func validateProbe(probe, podSpec gjson.Result, probeType string, containerName string, config ProbeConfig,
+	found *violations) {
+	if !probe.Exists() {
+		if config.Required {
+			found.addMissing(containerName, probeType)
+		}
+		return
+	}
+
+	validateProbeHandler(probe, probeType, containerName, config, found)
+
+	if probe.Get("httpGet").Exists() {
+		validateHTTPPath(probe.Get("httpGet"), probeType, containerName, config, found)
+	}
+
+	if config.RequireExplicitTimings {
+		validateExplicitTimings(probe, probeType, containerName, found)
 	}
 
-	if container.Get("livenessProbe").Exists() {
-		periodSeconds := container.Get("livenessProbe.periodSeconds").Int()
-		timeoutSeconds := container.Get("livenessProbe.timeoutSeconds").Int()
+	// Timing bounds may be overridden for the handler in use。
+	timingConfig := config
+	if handler, err := probeHandler(probe); err == nil {
+		timingConfig = config.forHandler(handler)
+	}
+	validateProbeTimings(probeType, containerName, effectiveProbeTimings(probe, podSpec), timingConfig, found)
+}
 
-		if err := validateProbeTimings("liveness", containerName, periodSeconds, timeoutSeconds, config); err != nil {
-			return err
+// probeHandler returns the handler defined by the probe。A probe must define exactly one handler。
+func probeHandler(probe gjson.Result) (string, error) {
+	handlers := []string{}
+	for _, handler := range probeHandlers {
+		if probe.Get(handler).Exists() {
+			handlers = append(handlers, handler)
 		}
 	}
 
-	return nil
+	switch len(handlers) {
+	case 0:
+		return "", errors.New("no handler defined")
+	case 1:
+		return handlers[0], nil
+	default:
+		return "", fmt.Errorf("several handlers defined (%s)", strings.Join(handlers, ", "))
+	}
 }
 
-// validateReadinessProbe validates the readiness probe configuration。
-func validateReadinessProbe(container gjson.Result, containerName string, config ProbeConfig) error {
-	if config.Required && !container.Get("readinessProbe").Exists() {
-		return fmt.Errorf("container '%s': missing readiness probe", containerName)
+// validateProbeHandler checks that the probe defines a single handler permitted by the configuration。
+func validateProbeHandler(probe gjson.Result, probeType string, containerName string, config ProbeConfig,
+	found *violations) {
+	handler, err := probeHandler(probe)
+	if err != nil {
+		found.add(containerName, probeType, "%s probe is malformed: %v", probeType, err)
+		return
+	}
+
+	if containsString(config.ForbiddenHandlers, handler) {
+		found.add(containerName, probeType, "%s probe handler '%s' is forbidden", probeType, handler)
 	}
 
-	if container.Get("readinessProbe").Exists() {
-		periodSeconds := container.Get("readinessProbe.periodSeconds").Int()
-		timeoutSeconds := container.Get("readinessProbe.timeoutSeconds").Int()
+	if len(config.AllowedHandlers) > 0 && !containsString(config.AllowedHandlers, handler) {
+		found.add(containerName, probeType, "%s probe handler '%s' is not allowed (allowed: %s)",
+			probeType, handler, strings.Join(config.AllowedHandlers, ", "))
+	}
+}
+
+// validateHTTPPath checks the httpGet path of a probe against the allowed and forbidden patterns。
+func validateHTTPPath(httpGet gjson.Result, probeType string, containerName string, config ProbeConfig,
+	found *violations) {
+	path := httpGet.Get("path").String()
+	if path == "" {
+		path = "/"
+	}
 
-		if err := validateProbeTimings("readiness", containerName, periodSeconds, timeoutSeconds, config); err != nil {
-			return err
+	if pattern, matched := firstMatchingPattern(config.ForbiddenPaths, path); matched {
+		found.add(containerName, probeType, "%s probe path '%s' matches forbidden pattern '%s'",
+			probeType, path, pattern)
+	}
+
+	if len(config.AllowedPaths) > 0 {
+		if _, matched := firstMatchingPattern(config.AllowedPaths, path); !matched {
+			found.add(containerName, probeType, "%s probe path '%s' does not match any allowed pattern (%s)",
+				probeType, path, strings.Join(config.AllowedPaths, ", "))
 		}
 	}
+}
 
-	return nil
+// validateHTTPProbeSecurity checks the httpGet probes of the container against the security rules:
+// no custom host outside the allowlist, no Host header override, no sensitive header and, when
+// required, the HTTPS scheme。Sensitive header values are never included in the violations。
+func validateHTTPProbeSecurity(container gjson.Result, containerName string, config HTTPProbeSecurityConfig,
+	found *violations) {
+	for _, probeType := range probeTypes {
+		httpGet := container.Get(probeType + "Probe.httpGet")
+		if !httpGet.Exists() {
+			continue
+		}
+
+		if host := httpGet.Get("host").String(); config.ForbidHost && host != "" {
+			if _, allowed := firstMatchingPattern(config.AllowedHosts, host); !allowed {
+				found.add(containerName, probeType, "%s probe httpGet.host '%s' is not allowed", probeType, host)
+			}
+		}
+
+		httpGet.Get("httpHeaders").ForEach(func(_, header gjson.Result) bool {
+			name := header.Get("name").String()
+			if config.ForbidHostHeader && strings.EqualFold(name, "Host") {
+				if _, allowed := firstMatchingPattern(config.AllowedHosts, header.Get("value").String()); !allowed {
+					found.add(containerName, probeType, "%s probe overrides the Host header with '%s'",
+						probeType, header.Get("value").String())
+				}
+			}
+			for _, sensitive := range config.SensitiveHeaders {
+				if strings.EqualFold(name, sensitive) {
+					found.add(containerName, probeType, "%s probe sets sensitive header '%s'", probeType, name)
+				}
+			}
+			return true
+		})
+
+		scheme := httpGet.Get("scheme").String()
+		if scheme == "" {
+			scheme = "HTTP"
+		}
+		if config.RequireHTTPS && scheme != "HTTPS" {
+			found.add(containerName, probeType, "%s probe httpGet.scheme is %s, HTTPS is required",
+				probeType, scheme)
+		}
+	}
 }
 
-// validateStartupProbe validates the startup probe configuration。
-func validateStartupProbe(container gjson.Result, containerName string, config ProbeConfig) error {
-	if config.Required && !container.Get("startupProbe").Exists() {
-		return fmt.Errorf("container '%s': missing startup probe", containerName)
+// validateExecProbes checks the commands of the exec probes of the container: no shell wrapper,
+// a first argument from the allowlist and a bounded length。
+func validateExecProbes(container gjson.Result, containerName string, config ExecProbeConfig, found *violations) {
+	for _, probeType := range probeTypes {
+		exec := container.Get(probeType + "Probe.exec")
+		if !exec.Exists() {
+			continue
+		}
+
+		command := []string{}
+		exec.Get("command").ForEach(func(_, arg gjson.Result) bool {
+			command = append(command, arg.String())
+			return true
+		})
+		if len(command) == 0 {
+			found.add(containerName, probeType, "%s probe is malformed: empty exec command", probeType)
+			continue
+		}
+
+		if config.ForbidShellWrappers && isShellWrapper(command) {
+			found.add(containerName, probeType, "%s probe exec command %q uses a shell wrapper", probeType, command)
+		}
+
+		if len(config.AllowedCommands) > 0 {
+			_, allowed := firstMatchingPattern(config.AllowedCommands, command[0])
+			if !allowed {
+				_, allowed = firstMatchingPattern(config.AllowedCommands, path.Base(command[0]))
+			}
+			if !allowed {
+				found.add(containerName, probeType, "%s probe exec command %q runs '%s', which is not allowed (allowed: %s)",
+					probeType, command, command[0], strings.Join(config.AllowedCommands, ", "))
+			}
+		}
+
+		if length := len(strings.Join(command, " ")); config.MaxCommandLength > 0 &&
+			length > int(config.MaxCommandLength) {
+			found.add(containerName, probeType, "%s probe exec command %q is %d characters long, "+
+				"exceeds maximum allowed (%d)", probeType, command, length, config.MaxCommandLength)
+		}
 	}
+}
 
-	if container.Get("startupProbe").Exists() {
-		periodSeconds := container.Get("startupProbe.periodSeconds").Int()
-		timeoutSeconds := container.Get("startupProbe.timeoutSeconds").Int()
+// isShellWrapper reports whether the command runs a shell with an inline script, such as
+// "sh -c", "/bin/bash -ec" or "env sh -c"。Only the shell options are scanned: the first other
+// argument is the script path, and the arguments after it belong to the script。
+func isShellWrapper(command []string) bool {
+	command = withoutCommandWrappers(command)
+	if len(command) == 0 || !containsString(shells, path.Base(command[0])) {
+		return false
+	}
+	for i := 1; i < len(command); i++ {
+		arg := command[i]
+		if arg == "--" || (!strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "+")) {
+			return false
+		}
+		if containsString(shellOptionsWithArgument, arg) {
+			// The option argument is a separate argument。
+			i++
+			continue
+		}
+		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.Contains(arg, "c") {
+			return true
+		}
+	}
+	return false
+}
 
-		if err := validateProbeTimings("startup", containerName, periodSeconds, timeoutSeconds, config); err != nil {
-			return err
+// withoutCommandWrappers drops the env and busybox wrappers, with their options and variable
+// assignments, from the start of the command。
+func withoutCommandWrappers(command []string) []string {
+	for len(command) > 0 {
+		switch path.Base(command[0]) {
+		case "busybox":
+			command = command[1:]
+		case "env":
+			i := 1
+			for ; i < len(command); i++ {
+				arg := command[i]
+				if containsString(envOptionsWithArgument, arg) {
+					i++
+					continue
+				}
+				if !strings.HasPrefix(arg, "-") && !strings.Contains(arg, "=") {
+					break
+				}
+			}
+			command = command[min(i, len(command)):]
+		default:
+			return command
 		}
 	}
+	return command
+}
 
-	return nil
+// validateProbeRelations compares the liveness, readiness and startup probes of the container。
+// Each rule is only evaluated when both probes it compares are defined。
+func validateProbeRelations(container, podSpec gjson.Result, containerName string, config ProbeRelationsConfig,
+	found *violations) {
+	liveness := container.Get("livenessProbe")
+	readiness := container.Get("readinessProbe")
+	startup := container.Get("startupProbe")
+
+	if config.LivenessSlowerThanReadiness && liveness.Exists() && readiness.Exists() {
+		livenessWindow := effectiveProbeTimings(liveness, podSpec).failureWindow()
+		readinessWindow := effectiveProbeTimings(readiness, podSpec).failureWindow()
+		if livenessWindow < readinessWindow {
+			found.add(containerName, "liveness",
+				"liveness probe failure window (%ds) is shorter than readiness probe failure window (%ds)",
+				livenessWindow, readinessWindow)
+		}
+	}
+
+	if config.DistinctLivenessReadiness && liveness.Exists() && readiness.Exists() &&
+		sameJSON(liveness.Raw, readiness.Raw) {
+		found.add(containerName, "liveness", "liveness probe is identical to readiness probe")
+	}
+
+	if config.StartupMatchesLivenessPort && liveness.Exists() && startup.Exists() {
+		_, livenessPort, livenessHasPort := probePort(liveness)
+		_, startupPort, startupHasPort := probePort(startup)
+		switch {
+		case livenessHasPort && !startupHasPort:
+			found.add(containerName, "startup", "startup probe targets no port while liveness probe targets port %s",
+				resolvePort(container, livenessPort))
+		case livenessHasPort && resolvePort(container, livenessPort) != resolvePort(container, startupPort):
+			found.add(containerName, "startup", "startup probe targets port %s while liveness probe targets port %s",
+				resolvePort(container, startupPort), resolvePort(container, livenessPort))
+		}
+	}
+}
+
+// validateStartupBudget checks that the kubelet tolerates a startup of at least maxStartupSeconds
+// before considering the container failed。The budget is the startup probe initial delay plus its
+// failure window or, without a startup probe, the same values of the liveness probe。Containers
+// without either probe are never restarted by a probe and have no budget to check。
+func validateStartupBudget(container, podSpec gjson.Result, containerName string, maxStartupSeconds int32,
+	found *violations) {
+	probeType := "startup"
+	probe := container.Get("startupProbe")
+	if !probe.Exists() {
+		probeType = "liveness"
+		probe = container.Get("livenessProbe")
+	}
+	if !probe.Exists() {
+		return
+	}
+
+	timings := effectiveProbeTimings(probe, podSpec)
+	budget := timings.InitialDelaySeconds + timings.failureWindow()
+	if budget < int64(maxStartupSeconds) {
+		found.add(containerName, probeType,
+			"startup budget is %ds (%s probe initialDelaySeconds %d + periodSeconds %d × failureThreshold %d), "+
+				"less than max_startup_seconds (%ds)",
+			budget, probeType, timings.InitialDelaySeconds, timings.PeriodSeconds, timings.FailureThreshold,
+			maxStartupSeconds)
+	}
+}
+
+// resolvePort returns the number of a named port declared by the container, or the port as is。
+func resolvePort(container, port gjson.Result) string {
+	if port.Type == gjson.String {
+		if declared := container.Get(fmt.Sprintf(`ports.#(name==%q).containerPort`, port.String())); declared.Exists() {
+			return declared.String()
+		}
+		return "'" + port.String() + "'"
+	}
+	return port.String()
+}
+
+// sameJSON reports whether two JSON documents hold the same values, regardless of formatting and
+// key order。
+func sameJSON(a, b string) bool {
+	var decodedA, decodedB interface{}
+	if json.Unmarshal([]byte(a), &decodedA) != nil || json.Unmarshal([]byte(b), &decodedB) != nil {
+		return false
+	}
+	return reflect.DeepEqual(decodedA, decodedB)
+}
+
+// containsString reports whether value is part of values。
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// probePort returns the port targeted by the probe handler, along with the handler name。exec
+// probes do not target any port。
+func probePort(probe gjson.Result) (string, gjson.Result, bool) {
+	for _, handler := range []string{"httpGet", "tcpSocket", "grpc"} {
+		if port := probe.Get(handler + ".port"); port.Exists() {
+			return handler, port, true
+		}
+	}
+	return "", gjson.Result{}, false
+}
+
+// validateProbePorts checks that every probe of the container targets a port the pod exposes。
+// Numeric ports are looked up in the container ports and, when allowed, in the ports of the
+// other containers of the pod, which share its network namespace。Named ports are only resolved
+// by the kubelet against the ports of the container itself。
+func validateProbePorts(container, podSpec gjson.Result, containerName string, config ProbePortsConfig,
+	found *violations) {
+	for _, probeType := range probeTypes {
+		handler, port, ok := probePort(container.Get(probeType + "Probe"))
+		if !ok {
+			continue
+		}
+
+		if port.Type == gjson.String {
+			if !container.Get(fmt.Sprintf(`ports.#(name==%q)`, port.String())).Exists() {
+				found.add(containerName, probeType,
+					"%s probe %s.port '%s' does not match any named port of the container",
+					probeType, handler, port.String())
+			}
+			continue
+		}
+
+		if containerDeclaresPort(container, port.Int()) {
+			continue
+		}
+		if config.AllowOtherContainers && podDeclaresPort(podSpec, port.Int()) {
+			continue
+		}
+
+		scope := "container"
+		if config.AllowOtherContainers {
+			scope = "pod"
+		}
+		found.add(containerName, probeType, "%s probe %s.port %d is not declared by the %s",
+			probeType, handler, port.Int(), scope)
+	}
+}
+
+// containerDeclaresPort reports whether the container declares the given containerPort。
+func containerDeclaresPort(container gjson.Result, port int64) bool {
+	return container.Get(fmt.Sprintf("ports.#(containerPort==%d)", port)).Exists()
+}
+
+// podDeclaresPort reports whether any container of the pod declares the given containerPort。
+func podDeclaresPort(podSpec gjson.Result, port int64) bool {
+	declared := false
+	podSpec.Get("containers").ForEach(func(_, container gjson.Result) bool {
+		declared = containerDeclaresPort(container, port)
+		return !declared
+	})
+	return declared
+}
+
+// effectiveProbeTimings returns the timing values the kubelet uses for the probe, applying the
+// Kubernetes defaults to the fields that are unset。The probe-level terminationGracePeriodSeconds
+// falls back to the one of the pod spec, where zero is a valid value asking for an immediate kill。
+func effectiveProbeTimings(probe, podSpec gjson.Result) probeTimings {
+	podGracePeriod := int64(defaultTerminationGracePeriodSeconds)
+	if value := podSpec.Get("terminationGracePeriodSeconds"); value.Exists() && value.Int() >= 0 {
+		podGracePeriod = value.Int()
+	}
+	return probeTimings{
+		InitialDelaySeconds: probeField(probe, "initialDelaySeconds", defaultInitialDelaySeconds),
+		PeriodSeconds:       probeField(probe, "periodSeconds", defaultPeriodSeconds),
+		TimeoutSeconds:      probeField(probe, "timeoutSeconds", defaultTimeoutSeconds),
+		SuccessThreshold:    probeField(probe, "successThreshold", defaultSuccessThreshold),
+		FailureThreshold:    probeField(probe, "failureThreshold", defaultFailureThreshold),
+
+		TerminationGracePeriodSeconds: probeField(probe, "terminationGracePeriodSeconds", podGracePeriod),
+	}
+}
+
+// probeField returns the value of a probe field, or defaultValue when it is unset。A zero value is
+// treated as unset, the same way the API server defaults it。
+func probeField(probe gjson.Result, field string, defaultValue int64) int64 {
+	value := probe.Get(field)
+	if !value.Exists() || value.Int() <= 0 {
+		return defaultValue
+	}
+	return value.Int()
+}
+
+// validateExplicitTimings rejects probes that rely on the Kubernetes defaults for their timings。
+func validateExplicitTimings(probe gjson.Result, probeType string, containerName string, found *violations) {
+	implicit := []string{}
+	for _, field := range explicitTimingFields {
+		if !probe.Get(field.name).Exists() {
+			implicit = append(implicit, fmt.Sprintf("%s (%d)", field.name, field.defaultValue))
+		}
+	}
+
+	if len(implicit) > 0 {
+		found.add(containerName, probeType, "%s probe relies on implicit defaults for %s",
+			probeType, strings.Join(implicit, ", "))
+	}
 }
 
 // validateProbeTimings validates the timing parameters of a probe。
-func validateProbeTimings(probeType string, containerName string, periodSeconds,
-	timeoutSeconds int64, config ProbeConfig) error {
-	if config.MinPeriodSeconds > 0 && periodSeconds < int64(config.MinPeriodSeconds) {
-		return fmt.Errorf(
-			"container '%s': %s probe period (%ds) is less than minimum required (%ds)",
-			containerName,
-			probeType,
-			periodSeconds,
-			config.MinPeriodSeconds,
-		)
+func validateProbeTimings(probeType string, containerName string, timings probeTimings,
+	config ProbeConfig, found *violations) {
+	checks := []struct {
+		field            string
+		value            int64
+		minimum, maximum int32
+		unit             string
+	}{
+		{"initialDelaySeconds", timings.InitialDelaySeconds,
+			config.MinInitialDelaySeconds, config.MaxInitialDelaySeconds, "s"},
+		{"periodSeconds", timings.PeriodSeconds, config.MinPeriodSeconds, config.MaxPeriodSeconds, "s"},
+		{"timeoutSeconds", timings.TimeoutSeconds, config.MinTimeoutSeconds, config.MaxTimeoutSeconds, "s"},
+		{"successThreshold", timings.SuccessThreshold, config.MinSuccessThreshold, config.MaxSuccessThreshold, ""},
+		{"failureThreshold", timings.FailureThreshold, config.MinFailureThreshold, config.MaxFailureThreshold, ""},
+		{"terminationGracePeriodSeconds", timings.TerminationGracePeriodSeconds,
+			config.MinTerminationGracePeriodSeconds, config.MaxTerminationGracePeriodSeconds, "s"},
 	}
 
-	if config.MaxTimeoutSeconds > 0 && timeoutSeconds > int64(config.MaxTimeoutSeconds) {
-		return fmt.Errorf("container '%s': %s probe timeout (%ds) exceeds maximum allowed (%ds)",
-			containerName, probeType, timeoutSeconds, config.MaxTimeoutSeconds)
+	for _, check := range checks {
+		if check.minimum > 0 && check.value < int64(check.minimum) {
+			found.add(containerName, probeType, "%s probe %s (%d%s) is less than minimum required (%d%s)",
+				probeType, check.field, check.value, check.unit, check.minimum, check.unit)
+		}
+
+		if check.maximum > 0 && check.value > int64(check.maximum) {
+			found.add(containerName, probeType, "%s probe %s (%d%s) exceeds maximum allowed (%d%s)",
+				probeType, check.field, check.value, check.unit, check.maximum, check.unit)
+		}
 	}
 
-	return nil
+	ratio := float64(timings.TimeoutSeconds) / float64(timings.PeriodSeconds)
+	if config.MaxTimeoutToPeriodRatio > 0 && ratio > config.MaxTimeoutToPeriodRatio {
+		found.add(containerName, probeType,
+			"%s probe timeoutSeconds/periodSeconds ratio is %.2f (%ds/%ds), exceeds max_timeout_to_period_ratio (%.2f)",
+			probeType, ratio, timings.TimeoutSeconds, timings.PeriodSeconds, config.MaxTimeoutToPeriodRatio)
+	}
+
+	if detection := timings.detectionTime(); config.MaxDetectionSeconds > 0 &&
+		detection > int64(config.MaxDetectionSeconds) {
+		found.add(containerName, probeType,
+			"%s probe worst-case detection time is %ds (periodSeconds %d × failureThreshold %d + timeoutSeconds %d), "+
+				"exceeds max_detection_seconds (%ds)",
+			probeType, detection, timings.PeriodSeconds, timings.FailureThreshold, timings.TimeoutSeconds,
+			config.MaxDetectionSeconds)
+	}
 }
@@ -2,6 +2,9 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
 	"testing"
 
 	kubewarden_protocol "github.com/kubewarden/policy-sdk-go/protocol"
@@ -200,3 +203,1585 @@ func TestValidateDeploymentProbes(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatePodControllerKinds(t *testing.T) {
+	tests := []struct {
+		name            string
+		fixture         string
+		settings        string
+		shouldAllow     bool
+		expectedMessage string
+	}{
+		{
+			name:            "reject pod without readiness probe",
+			fixture:         "test_data/pod.json",
+			settings:        `{}`,
+			shouldAllow:     false,
+			expectedMessage: "container 'pause': missing readiness probe",
+		},
+		{
+			name:        "accept pod with optional probes",
+			fixture:     "test_data/pod.json",
+			settings:    `{"readiness_probe": {"required": false}}`,
+			shouldAllow: true,
+		},
+		{
+			name:        "accept deployment",
+			fixture:     "test_data/deployment-valid.json",
+			settings:    `{"liveness_probe": {"required": true}}`,
+			shouldAllow: true,
+		},
+		{
+			name:        "accept replicaset",
+			fixture:     "test_data/replicaset.json",
+			settings:    `{"liveness_probe": {"required": true}}`,
+			shouldAllow: true,
+		},
+		{
+			name:        "accept statefulset",
+			fixture:     "test_data/statefulset.json",
+			settings:    `{"liveness_probe": {"required": true}}`,
+			shouldAllow: true,
+		},
+		{
+			name:        "accept daemonset",
+			fixture:     "test_data/daemonset.json",
+			settings:    `{"liveness_probe": {"required": true}}`,
+			shouldAllow: true,
+		},
+		{
+			name:        "accept job",
+			fixture:     "test_data/job.json",
+			settings:    `{"liveness_probe": {"required": true}}`,
+			shouldAllow: true,
+		},
+		{
+			name:        "accept cronjob",
+			fixture:     "test_data/cronjob.json",
+			settings:    `{"liveness_probe": {"required": true}}`,
+			shouldAllow: true,
+		},
+		{
+			name:        "accept replicationcontroller",
+			fixture:     "test_data/replicationcontroller.json",
+			settings:    `{"liveness_probe": {"required": true}}`,
+			shouldAllow: true,
+		},
+		{
+			name:        "reject cronjob with invalid probe period",
+			fixture:     "test_data/cronjob.json",
+			settings:    `{"readiness_probe": {"required": true, "min_period_seconds": 10}}`,
+			shouldAllow: false,
+			expectedMessage: "container 'test-container': readiness probe periodSeconds (5s) " +
+				"is less than minimum required (10s)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := validateFixture(t, test.fixture, test.settings)
+
+			if response.Accepted != test.shouldAllow {
+				t.Fatalf("Expected validation to return %v, got %v. Message: %v",
+					test.shouldAllow, response.Accepted, response.Message)
+			}
+
+			if test.expectedMessage != "" && *response.Message != test.expectedMessage {
+				t.Errorf("Expected message %q, got %q", test.expectedMessage, *response.Message)
+			}
+		})
+	}
+}
+
+func TestValidateUnsupportedKind(t *testing.T) {
+	request := kubewarden_protocol.ValidationRequest{
+		Request: kubewarden_protocol.KubernetesAdmissionRequest{
+			Kind:   kubewarden_protocol.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"},
+			Object: json.RawMessage(`{"apiVersion": "v1", "kind": "ConfigMap", "data": {}}`),
+		},
+		Settings: json.RawMessage(`{}`),
+	}
+
+	response := validateRequest(t, request)
+	if response.Accepted {
+		t.Fatal("Expected ConfigMap to be rejected")
+	}
+	expected := "unsupported kind 'ConfigMap': object should be one of these kinds: " +
+		"Deployment, ReplicaSet, StatefulSet, DaemonSet, ReplicationController, Job, CronJob, Pod"
+	if *response.Message != expected {
+		t.Errorf("Expected message %q, got %q", expected, *response.Message)
+	}
+}
+
+// validateFixture runs validate against an admission request stored in test_data。
+func validateFixture(t *testing.T, fixture string, settings string) kubewarden_protocol.ValidationResponse {
+	t.Helper()
+
+	admissionRequest, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatalf("cannot read fixture %s: %v", fixture, err)
+	}
+
+	request := kubewarden_protocol.ValidationRequest{
+		Settings: json.RawMessage(settings),
+	}
+	if err = json.Unmarshal(admissionRequest, &request.Request); err != nil {
+		t.Fatalf("cannot unmarshal fixture %s: %v", fixture, err)
+	}
+
+	return validateRequest(t, request)
+}
+
+// assertValidateDeployment validates the object and checks that it is accepted when
+// expectedMessage is empty, or rejected with expectedMessage otherwise。It returns the validation
+// error for further checks。
+func assertValidateDeployment(t *testing.T, kind string, object []byte, settings Settings,
+	expectedMessage string) error {
+	t.Helper()
+
+	err := validateDeployment(kind, object, settings)
+	if expectedMessage == "" {
+		if err != nil {
+			t.Errorf("Expected deployment to be accepted, got: %v", err)
+		}
+		return err
+	}
+
+	if err == nil {
+		t.Fatalf("Expected deployment to be rejected with %q", expectedMessage)
+	}
+	if err.Error() != expectedMessage {
+		t.Errorf("Expected message %q, got %q", expectedMessage, err.Error())
+	}
+	return err
+}
+
+// validateRequest marshals the request, calls validate and decodes its response。
+func validateRequest(t *testing.T,
+	request kubewarden_protocol.ValidationRequest) kubewarden_protocol.ValidationResponse {
+	t.Helper()
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+
+	responsePayload, err := validate(payload)
+	if err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+
+	var response kubewarden_protocol.ValidationResponse
+	if err = json.Unmarshal(responsePayload, &response); err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+
+	return response
+}
+
+func TestValidateReportsEveryViolation(t *testing.T) {
+	settings := Settings{
+		LivenessProbe:  ProbeConfig{Required: true, MinPeriodSeconds: 10, MaxTimeoutSeconds: 5},
+		ReadinessProbe: ProbeConfig{Required: true},
+	}
+	deployment := []byte(`{
+		"apiVersion": "apps/v1",
+		"kind": "Deployment",
+		"spec": {
+			"template": {
+				"spec": {
+					"containers": [
+						{
+							"name": "app",
+							"livenessProbe": {
+								"httpGet": {"path": "/healthz", "port": 8080},
+								"periodSeconds": 5,
+								"timeoutSeconds": 10
+							}
+						},
+						{
+							"name": "sidecar"
+						}
+					]
+				}
+			}
+		}
+	}`)
+
+	err := validateDeployment("Deployment", deployment, settings)
+	if err == nil {
+		t.Fatal("Expected deployment to be rejected")
+	}
+
+	expected := "container 'app': liveness probe periodSeconds (5s) is less than minimum required (10s); " +
+		"liveness probe timeoutSeconds (10s) exceeds maximum allowed (5s); missing readiness probe. " +
+		"container 'sidecar': missing liveness probe; missing readiness probe"
+	if err.Error() != expected {
+		t.Errorf("Expected message %q, got %q", expected, err.Error())
+	}
+}
+
+func TestValidateProbeDefaults(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          ProbeConfig
+		probe           string
+		expectedMessage string
+	}{
+		{
+			name:   "missing period uses kubelet default",
+			config: ProbeConfig{Required: true, MinPeriodSeconds: 10},
+			probe:  `{"httpGet": {"path": "/healthz", "port": 8080}}`,
+		},
+		{
+			name:            "missing period below minimum",
+			config:          ProbeConfig{Required: true, MinPeriodSeconds: 15},
+			probe:           `{"httpGet": {"path": "/healthz", "port": 8080}}`,
+			expectedMessage: "container 'app': liveness probe periodSeconds (10s) is less than minimum required (15s)",
+		},
+		{
+			name:   "missing timeout uses kubelet default",
+			config: ProbeConfig{Required: true, MaxTimeoutSeconds: 1},
+			probe:  `{"httpGet": {"path": "/healthz", "port": 8080}}`,
+		},
+		{
+			name:   "zero timeout is defaulted",
+			config: ProbeConfig{Required: true, MaxTimeoutSeconds: 1},
+			probe:  `{"httpGet": {"path": "/healthz", "port": 8080}, "timeoutSeconds": 0}`,
+		},
+		{
+			name:   "explicit timings present",
+			config: ProbeConfig{Required: true, RequireExplicitTimings: true},
+			probe: `{"httpGet": {"path": "/healthz", "port": 8080},
+				"initialDelaySeconds": 0, "periodSeconds": 10, "timeoutSeconds": 1,
+				"successThreshold": 1, "failureThreshold": 3}`,
+		},
+		{
+			name:   "explicit timings missing",
+			config: ProbeConfig{Required: true, RequireExplicitTimings: true},
+			probe:  `{"httpGet": {"path": "/healthz", "port": 8080}, "periodSeconds": 10}`,
+			expectedMessage: "container 'app': liveness probe relies on implicit defaults for " +
+				"initialDelaySeconds (0), timeoutSeconds (1), successThreshold (1), failureThreshold (3)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := Settings{LivenessProbe: test.config}
+			deployment := []byte(`{"spec": {"template": {"spec": {"containers": [
+				{"name": "app", "livenessProbe": ` + test.probe + `}
+			]}}}}`)
+
+			assertValidateDeployment(t, "Deployment", deployment, settings, test.expectedMessage)
+		})
+	}
+}
+
+func TestValidateProbeBounds(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          ProbeConfig
+		probe           string
+		podSpecFields   string
+		expectedMessage string
+	}{
+		{
+			name:            "period above maximum",
+			config:          ProbeConfig{MaxPeriodSeconds: 30},
+			probe:           `{"tcpSocket": {"port": 8080}, "periodSeconds": 60}`,
+			expectedMessage: "container 'app': liveness probe periodSeconds (60s) exceeds maximum allowed (30s)",
+		},
+		{
+			name:            "timeout below minimum",
+			config:          ProbeConfig{MinTimeoutSeconds: 2},
+			probe:           `{"tcpSocket": {"port": 8080}}`,
+			expectedMessage: "container 'app': liveness probe timeoutSeconds (1s) is less than minimum required (2s)",
+		},
+		{
+			name:   "initial delay outside bounds",
+			config: ProbeConfig{MinInitialDelaySeconds: 5, MaxInitialDelaySeconds: 60},
+			probe:  `{"tcpSocket": {"port": 8080}, "initialDelaySeconds": 120}`,
+			expectedMessage: "container 'app': liveness probe initialDelaySeconds (120s) " +
+				"exceeds maximum allowed (60s)",
+		},
+		{
+			name:            "failure threshold below minimum",
+			config:          ProbeConfig{MinFailureThreshold: 3},
+			probe:           `{"tcpSocket": {"port": 8080}, "failureThreshold": 1}`,
+			expectedMessage: "container 'app': liveness probe failureThreshold (1) is less than minimum required (3)",
+		},
+		{
+			name:            "success threshold above maximum",
+			config:          ProbeConfig{MaxSuccessThreshold: 1},
+			probe:           `{"tcpSocket": {"port": 8080}, "successThreshold": 2}`,
+			expectedMessage: "container 'app': liveness probe successThreshold (2) exceeds maximum allowed (1)",
+		},
+		{
+			name:          "termination grace period falls back to pod spec",
+			config:        ProbeConfig{MaxTerminationGracePeriodSeconds: 30},
+			probe:         `{"tcpSocket": {"port": 8080}}`,
+			podSpecFields: `"terminationGracePeriodSeconds": 120,`,
+			expectedMessage: "container 'app': liveness probe terminationGracePeriodSeconds (120s) " +
+				"exceeds maximum allowed (30s)",
+		},
+		{
+			name:          "probe termination grace period overrides pod spec",
+			config:        ProbeConfig{MaxTerminationGracePeriodSeconds: 30},
+			probe:         `{"tcpSocket": {"port": 8080}, "terminationGracePeriodSeconds": 10}`,
+			podSpecFields: `"terminationGracePeriodSeconds": 120,`,
+		},
+		{
+			name:          "zero pod termination grace period is kept",
+			config:        ProbeConfig{MaxTerminationGracePeriodSeconds: 10},
+			probe:         `{"tcpSocket": {"port": 8080}}`,
+			podSpecFields: `"terminationGracePeriodSeconds": 0,`,
+		},
+		{
+			name:          "zero pod termination grace period is below minimum",
+			config:        ProbeConfig{MinTerminationGracePeriodSeconds: 5},
+			probe:         `{"tcpSocket": {"port": 8080}}`,
+			podSpecFields: `"terminationGracePeriodSeconds": 0,`,
+			expectedMessage: "container 'app': liveness probe terminationGracePeriodSeconds (0s) " +
+				"is less than minimum required (5s)",
+		},
+		{
+			name: "every value within bounds",
+			config: ProbeConfig{
+				MinPeriodSeconds: 5, MaxPeriodSeconds: 30,
+				MinTimeoutSeconds: 1, MaxTimeoutSeconds: 3,
+				MinFailureThreshold: 3, MaxFailureThreshold: 5,
+			},
+			probe: `{"tcpSocket": {"port": 8080}, "periodSeconds": 10, "timeoutSeconds": 2}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := Settings{LivenessProbe: test.config}
+			deployment := []byte(`{"spec": {"template": {"spec": {` + test.podSpecFields + `"containers": [
+				{"name": "app", "livenessProbe": ` + test.probe + `}
+			]}}}}`)
+
+			assertValidateDeployment(t, "Deployment", deployment, settings, test.expectedMessage)
+		})
+	}
+}
+
+func TestValidateProbeHandlers(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          ProbeConfig
+		probe           string
+		expectedMessage string
+	}{
+		{
+			name:            "forbidden exec handler",
+			config:          ProbeConfig{ForbiddenHandlers: []string{"exec"}},
+			probe:           `{"exec": {"command": ["cat", "/tmp/healthy"]}}`,
+			expectedMessage: "container 'app': liveness probe handler 'exec' is forbidden",
+		},
+		{
+			name:   "handler not in allowed list",
+			config: ProbeConfig{AllowedHandlers: []string{"httpGet", "grpc"}},
+			probe:  `{"tcpSocket": {"port": 8080}}`,
+			expectedMessage: "container 'app': liveness probe handler 'tcpSocket' is not allowed " +
+				"(allowed: httpGet, grpc)",
+		},
+		{
+			name:   "handler in allowed list",
+			config: ProbeConfig{AllowedHandlers: []string{"httpGet", "grpc"}},
+			probe:  `{"grpc": {"port": 9090}}`,
+		},
+		{
+			name:            "probe without handler",
+			config:          ProbeConfig{},
+			probe:           `{"periodSeconds": 10}`,
+			expectedMessage: "container 'app': liveness probe is malformed: no handler defined",
+		},
+		{
+			name:   "probe with several handlers",
+			config: ProbeConfig{},
+			probe:  `{"httpGet": {"path": "/healthz", "port": 8080}, "tcpSocket": {"port": 8080}}`,
+			expectedMessage: "container 'app': liveness probe is malformed: " +
+				"several handlers defined (httpGet, tcpSocket)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := Settings{LivenessProbe: test.config}
+			deployment := []byte(`{"spec": {"template": {"spec": {"containers": [
+				{"name": "app", "livenessProbe": ` + test.probe + `}
+			]}}}}`)
+
+			assertValidateDeployment(t, "Deployment", deployment, settings, test.expectedMessage)
+		})
+	}
+}
+
+func TestValidateProbePorts(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          ProbePortsConfig
+		containers      string
+		expectedMessage string
+	}{
+		{
+			name:   "numeric port declared by the container",
+			config: ProbePortsConfig{Enabled: true},
+			containers: `{"name": "app", "ports": [{"containerPort": 8080}],
+				"readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}}`,
+		},
+		{
+			name:   "numeric port not declared",
+			config: ProbePortsConfig{Enabled: true},
+			containers: `{"name": "app", "ports": [{"containerPort": 8080}],
+				"readinessProbe": {"httpGet": {"path": "/ready", "port": 8081}}}`,
+			expectedMessage: "container 'app': readiness probe httpGet.port 8081 is not declared by the container",
+		},
+		{
+			name:   "check disabled",
+			config: ProbePortsConfig{},
+			containers: `{"name": "app", "ports": [{"containerPort": 8080}],
+				"readinessProbe": {"httpGet": {"path": "/ready", "port": 8081}}}`,
+		},
+		{
+			name:   "named port declared by the container",
+			config: ProbePortsConfig{Enabled: true},
+			containers: `{"name": "app", "ports": [{"name": "http-metrics", "containerPort": 9090}],
+				"readinessProbe": {"tcpSocket": {"port": "http-metrics"}}}`,
+		},
+		{
+			name:   "named port not declared",
+			config: ProbePortsConfig{Enabled: true},
+			containers: `{"name": "app", "ports": [{"name": "http", "containerPort": 8080}],
+				"readinessProbe": {"tcpSocket": {"port": "http-metrics"}}}`,
+			expectedMessage: "container 'app': readiness probe tcpSocket.port 'http-metrics' " +
+				"does not match any named port of the container",
+		},
+		{
+			name:   "grpc port declared by another container",
+			config: ProbePortsConfig{Enabled: true},
+			containers: `{"name": "app", "readinessProbe": {"grpc": {"port": 9090}}},
+				{"name": "proxy", "ports": [{"containerPort": 9090}],
+				 "readinessProbe": {"grpc": {"port": 9090}}}`,
+			expectedMessage: "container 'app': readiness probe grpc.port 9090 is not declared by the container",
+		},
+		{
+			name:   "grpc port declared by another container allowed",
+			config: ProbePortsConfig{Enabled: true, AllowOtherContainers: true},
+			containers: `{"name": "app", "readinessProbe": {"grpc": {"port": 9090}}},
+				{"name": "proxy", "ports": [{"containerPort": 9090}],
+				 "readinessProbe": {"grpc": {"port": 9090}}}`,
+		},
+		{
+			name:   "port not declared anywhere in the pod",
+			config: ProbePortsConfig{Enabled: true, AllowOtherContainers: true},
+			containers: `{"name": "app", "ports": [{"containerPort": 8080}],
+				"readinessProbe": {"tcpSocket": {"port": 8081}}}`,
+			expectedMessage: "container 'app': readiness probe tcpSocket.port 8081 is not declared by the pod",
+		},
+		{
+			name:       "exec probe has no port",
+			config:     ProbePortsConfig{Enabled: true},
+			containers: `{"name": "app", "readinessProbe": {"exec": {"command": ["true"]}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := Settings{ProbePorts: test.config}
+			deployment := []byte(`{"spec": {"template": {"spec": {"containers": [` + test.containers + `]}}}}`)
+
+			assertValidateDeployment(t, "Deployment", deployment, settings, test.expectedMessage)
+		})
+	}
+}
+
+func TestValidateHTTPPaths(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          ProbeConfig
+		probe           string
+		expectedMessage string
+	}{
+		{
+			name:   "forbidden glob pattern",
+			config: ProbeConfig{ForbiddenPaths: []string{"/health/*", "/actuator/health"}},
+			probe:  `{"httpGet": {"path": "/health/db", "port": 8080}}`,
+			expectedMessage: "container 'app': liveness probe path '/health/db' " +
+				"matches forbidden pattern '/health/*'",
+		},
+		{
+			name:   "forbidden regex pattern",
+			config: ProbeConfig{ForbiddenPaths: []string{"regex:^/actuator/health"}},
+			probe:  `{"httpGet": {"path": "/actuator/health/liveness", "port": 8080}}`,
+			expectedMessage: "container 'app': liveness probe path '/actuator/health/liveness' " +
+				"matches forbidden pattern 'regex:^/actuator/health'",
+		},
+		{
+			name:   "path not forbidden",
+			config: ProbeConfig{ForbiddenPaths: []string{"/health/*"}},
+			probe:  `{"httpGet": {"path": "/healthz", "port": 8080}}`,
+		},
+		{
+			name:   "allowed pattern matched",
+			config: ProbeConfig{AllowedPaths: []string{"/ready*"}},
+			probe:  `{"httpGet": {"path": "/readyz", "port": 8080}}`,
+		},
+		{
+			name:   "allowed pattern not matched",
+			config: ProbeConfig{AllowedPaths: []string{"/ready*"}},
+			probe:  `{"httpGet": {"path": "/status", "port": 8080}}`,
+			expectedMessage: "container 'app': liveness probe path '/status' " +
+				"does not match any allowed pattern (/ready*)",
+		},
+		{
+			name:   "missing path defaults to root",
+			config: ProbeConfig{AllowedPaths: []string{"/ready*"}},
+			probe:  `{"httpGet": {"port": 8080}}`,
+			expectedMessage: "container 'app': liveness probe path '/' " +
+				"does not match any allowed pattern (/ready*)",
+		},
+		{
+			name:   "non http probe is ignored",
+			config: ProbeConfig{AllowedPaths: []string{"/ready*"}},
+			probe:  `{"tcpSocket": {"port": 8080}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := Settings{LivenessProbe: test.config}
+			deployment := []byte(`{"spec": {"template": {"spec": {"containers": [
+				{"name": "app", "livenessProbe": ` + test.probe + `}
+			]}}}}`)
+
+			assertValidateDeployment(t, "Deployment", deployment, settings, test.expectedMessage)
+		})
+	}
+}
+
+func TestValidateHTTPProbeSecurity(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          HTTPProbeSecurityConfig
+		probe           string
+		expectedMessage string
+	}{
+		{
+			name:            "custom host forbidden",
+			config:          HTTPProbeSecurityConfig{ForbidHost: true},
+			probe:           `{"httpGet": {"host": "10.0.0.1", "path": "/ready", "port": 8080}}`,
+			expectedMessage: "container 'app': readiness probe httpGet.host '10.0.0.1' is not allowed",
+		},
+		{
+			name:   "custom host in allowlist",
+			config: HTTPProbeSecurityConfig{ForbidHost: true, AllowedHosts: []string{"localhost", "127.0.0.*"}},
+			probe:  `{"httpGet": {"host": "127.0.0.1", "path": "/ready", "port": 8080}}`,
+		},
+		{
+			name:   "custom host allowed when not forbidden",
+			config: HTTPProbeSecurityConfig{},
+			probe:  `{"httpGet": {"host": "10.0.0.1", "path": "/ready", "port": 8080}}`,
+		},
+		{
+			name:   "host header override forbidden",
+			config: HTTPProbeSecurityConfig{ForbidHostHeader: true},
+			probe: `{"httpGet": {"path": "/ready", "port": 8080,
+				"httpHeaders": [{"name": "host", "value": "metadata.internal"}]}}`,
+			expectedMessage: "container 'app': readiness probe overrides the Host header with 'metadata.internal'",
+		},
+		{
+			name:   "host header override in allowlist",
+			config: HTTPProbeSecurityConfig{ForbidHostHeader: true, AllowedHosts: []string{"app.local"}},
+			probe: `{"httpGet": {"path": "/ready", "port": 8080,
+				"httpHeaders": [{"name": "Host", "value": "app.local"}]}}`,
+		},
+		{
+			name:   "sensitive header",
+			config: HTTPProbeSecurityConfig{SensitiveHeaders: []string{"Authorization", "Cookie"}},
+			probe: `{"httpGet": {"path": "/ready", "port": 8080,
+				"httpHeaders": [{"name": "X-Probe", "value": "1"}, {"name": "authorization", "value": "Bearer abc"}]}}`,
+			expectedMessage: "container 'app': readiness probe sets sensitive header 'authorization'",
+		},
+		{
+			name:            "https required",
+			config:          HTTPProbeSecurityConfig{RequireHTTPS: true},
+			probe:           `{"httpGet": {"path": "/ready", "port": 8080}}`,
+			expectedMessage: "container 'app': readiness probe httpGet.scheme is HTTP, HTTPS is required",
+		},
+		{
+			name:   "https used",
+			config: HTTPProbeSecurityConfig{RequireHTTPS: true},
+			probe:  `{"httpGet": {"path": "/ready", "port": 8443, "scheme": "HTTPS"}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := Settings{HTTPProbeSecurity: test.config}
+			deployment := []byte(`{"spec": {"template": {"spec": {"containers": [
+				{"name": "app", "readinessProbe": ` + test.probe + `}
+			]}}}}`)
+
+			err := assertValidateDeployment(t, "Deployment", deployment, settings, test.expectedMessage)
+			if err != nil && strings.Contains(err.Error(), "Bearer") {
+				t.Errorf("Expected message not to leak header values, got %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestValidateExecProbes(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          ExecProbeConfig
+		probe           string
+		expectedMessage string
+	}{
+		{
+			name:   "shell wrapper forbidden",
+			config: ExecProbeConfig{ForbidShellWrappers: true},
+			probe:  `{"exec": {"command": ["sh", "-c", "cat /tmp/healthy"]}}`,
+			expectedMessage: "container 'app': liveness probe exec command " +
+				`["sh" "-c" "cat /tmp/healthy"] uses a shell wrapper`,
+		},
+		{
+			name:   "shell wrapper with absolute path and combined flags",
+			config: ExecProbeConfig{ForbidShellWrappers: true},
+			probe:  `{"exec": {"command": ["/bin/bash", "-ec", "curl -f localhost"]}}`,
+			expectedMessage: "container 'app': liveness probe exec command " +
+				`["/bin/bash" "-ec" "curl -f localhost"] uses a shell wrapper`,
+		},
+		{
+			name:   "shell running a script file",
+			config: ExecProbeConfig{ForbidShellWrappers: true},
+			probe:  `{"exec": {"command": ["sh", "/probe.sh"]}}`,
+		},
+		{
+			name:   "shell script with its own options",
+			config: ExecProbeConfig{ForbidShellWrappers: true},
+			probe:  `{"exec": {"command": ["/bin/sh", "/check.sh", "-config"]}}`,
+		},
+		{
+			name:   "shell wrapper after shell options",
+			config: ExecProbeConfig{ForbidShellWrappers: true},
+			probe:  `{"exec": {"command": ["bash", "-o", "pipefail", "-c", "curl -f localhost"]}}`,
+			expectedMessage: "container 'app': liveness probe exec command " +
+				`["bash" "-o" "pipefail" "-c" "curl -f localhost"] uses a shell wrapper`,
+		},
+		{
+			name:   "shell wrapper after an rcfile",
+			config: ExecProbeConfig{ForbidShellWrappers: true},
+			probe:  `{"exec": {"command": ["bash", "--rcfile", "f", "-c", "curl -f localhost"]}}`,
+			expectedMessage: "container 'app': liveness probe exec command " +
+				`["bash" "--rcfile" "f" "-c" "curl -f localhost"] uses a shell wrapper`,
+		},
+		{
+			name:   "shell wrapper after an init file",
+			config: ExecProbeConfig{ForbidShellWrappers: true},
+			probe:  `{"exec": {"command": ["bash", "--init-file", "f", "-c", "curl -f localhost"]}}`,
+			expectedMessage: "container 'app': liveness probe exec command " +
+				`["bash" "--init-file" "f" "-c" "curl -f localhost"] uses a shell wrapper`,
+		},
+		{
+			name:   "shell wrapper after a shopt option",
+			config: ExecProbeConfig{ForbidShellWrappers: true},
+			probe:  `{"exec": {"command": ["bash", "-O", "extglob", "+O", "nullglob", "-c", "ls"]}}`,
+			expectedMessage: "container 'app': liveness probe exec command " +
+				`["bash" "-O" "extglob" "+O" "nullglob" "-c" "ls"] uses a shell wrapper`,
+		},
+		{
+			name:   "shell wrapper run through env",
+			config: ExecProbeConfig{ForbidShellWrappers: true},
+			probe:  `{"exec": {"command": ["/usr/bin/env", "-u", "HOME", "LANG=C", "sh", "-c", "ls"]}}`,
+			expectedMessage: "container 'app': liveness probe exec command " +
+				`["/usr/bin/env" "-u" "HOME" "LANG=C" "sh" "-c" "ls"] uses a shell wrapper`,
+		},
+		{
+			name:   "shell wrapper run through busybox",
+			config: ExecProbeConfig{ForbidShellWrappers: true},
+			probe:  `{"exec": {"command": ["busybox", "sh", "-c", "ls"]}}`,
+			expectedMessage: "container 'app': liveness probe exec command " +
+				`["busybox" "sh" "-c" "ls"] uses a shell wrapper`,
+		},
+		{
+			name:   "env running a binary",
+			config: ExecProbeConfig{ForbidShellWrappers: true},
+			probe:  `{"exec": {"command": ["env", "LANG=C", "pg_isready", "-c", "x"]}}`,
+		},
+		{
+			name:   "command in allowlist",
+			config: ExecProbeConfig{AllowedCommands: []string{"grpc_health_probe", "/usr/bin/pg_isready"}},
+			probe:  `{"exec": {"command": ["/bin/grpc_health_probe", "-addr=:9090"]}}`,
+		},
+		{
+			name:   "command not in allowlist",
+			config: ExecProbeConfig{AllowedCommands: []string{"grpc_health_probe"}},
+			probe:  `{"exec": {"command": ["cat", "/tmp/healthy"]}}`,
+			expectedMessage: "container 'app': liveness probe exec command " +
+				`["cat" "/tmp/healthy"] runs 'cat', which is not allowed (allowed: grpc_health_probe)`,
+		},
+		{
+			name:   "command too long",
+			config: ExecProbeConfig{MaxCommandLength: 10},
+			probe:  `{"exec": {"command": ["cat", "/tmp/healthy"]}}`,
+			expectedMessage: "container 'app': liveness probe exec command " +
+				`["cat" "/tmp/healthy"] is 16 characters long, exceeds maximum allowed (10)`,
+		},
+		{
+			name:            "empty command",
+			config:          ExecProbeConfig{},
+			probe:           `{"exec": {"command": []}}`,
+			expectedMessage: "container 'app': liveness probe is malformed: empty exec command",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := Settings{ExecProbe: test.config}
+			deployment := []byte(`{"spec": {"template": {"spec": {"containers": [
+				{"name": "app", "livenessProbe": ` + test.probe + `}
+			]}}}}`)
+
+			assertValidateDeployment(t, "Deployment", deployment, settings, test.expectedMessage)
+		})
+	}
+}
+
+func TestValidateProbeRelations(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          ProbeRelationsConfig
+		container       string
+		expectedMessage string
+	}{
+		{
+			name:   "liveness slower than readiness",
+			config: ProbeRelationsConfig{LivenessSlowerThanReadiness: true},
+			container: `{"name": "app",
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "periodSeconds": 10},
+				"readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}, "periodSeconds": 5}}`,
+		},
+		{
+			name:   "liveness faster than readiness",
+			config: ProbeRelationsConfig{LivenessSlowerThanReadiness: true},
+			container: `{"name": "app",
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "periodSeconds": 5, "failureThreshold": 2},
+				"readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}}`,
+			expectedMessage: "container 'app': liveness probe failure window (10s) is shorter than " +
+				"readiness probe failure window (30s)",
+		},
+		{
+			name:   "identical liveness and readiness",
+			config: ProbeRelationsConfig{DistinctLivenessReadiness: true},
+			container: `{"name": "app",
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "periodSeconds": 10},
+				"readinessProbe": {"periodSeconds": 10, "httpGet": {"port": 8080, "path": "/healthz"}}}`,
+			expectedMessage: "container 'app': liveness probe is identical to readiness probe",
+		},
+		{
+			name:   "distinct liveness and readiness",
+			config: ProbeRelationsConfig{DistinctLivenessReadiness: true},
+			container: `{"name": "app",
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}},
+				"readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}}`,
+		},
+		{
+			name:   "startup uses liveness named port",
+			config: ProbeRelationsConfig{StartupMatchesLivenessPort: true},
+			container: `{"name": "app", "ports": [{"name": "http", "containerPort": 8080}],
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}},
+				"startupProbe": {"tcpSocket": {"port": "http"}}}`,
+		},
+		{
+			name:   "startup uses another port",
+			config: ProbeRelationsConfig{StartupMatchesLivenessPort: true},
+			container: `{"name": "app",
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}},
+				"startupProbe": {"tcpSocket": {"port": 9090}}}`,
+			expectedMessage: "container 'app': startup probe targets port 9090 while liveness probe targets port 8080",
+		},
+		{
+			name:   "startup uses exec",
+			config: ProbeRelationsConfig{StartupMatchesLivenessPort: true},
+			container: `{"name": "app",
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}},
+				"startupProbe": {"exec": {"command": ["true"]}}}`,
+			expectedMessage: "container 'app': startup probe targets no port while liveness probe targets port 8080",
+		},
+		{
+			name: "rules skipped when probes are missing",
+			config: ProbeRelationsConfig{
+				LivenessSlowerThanReadiness: true,
+				DistinctLivenessReadiness:   true,
+				StartupMatchesLivenessPort:  true,
+			},
+			container: `{"name": "app", "readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := Settings{ProbeRelations: test.config}
+			deployment := []byte(`{"spec": {"template": {"spec": {"containers": [` + test.container + `]}}}}`)
+
+			assertValidateDeployment(t, "Deployment", deployment, settings, test.expectedMessage)
+		})
+	}
+}
+
+func TestValidateStartupBudget(t *testing.T) {
+	tests := []struct {
+		name            string
+		container       string
+		expectedMessage string
+	}{
+		{
+			name: "startup probe budget large enough",
+			container: `{"name": "app",
+				"startupProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "periodSeconds": 10, "failureThreshold": 30},
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}}}`,
+		},
+		{
+			name: "startup probe budget too small",
+			container: `{"name": "app",
+				"startupProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "initialDelaySeconds": 5},
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "initialDelaySeconds": 300}}`,
+			expectedMessage: "container 'app': startup budget is 35s (startup probe initialDelaySeconds 5 + " +
+				"periodSeconds 10 × failureThreshold 3), less than max_startup_seconds (120s)",
+		},
+		{
+			name: "liveness probe budget without startup probe",
+			container: `{"name": "app",
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "initialDelaySeconds": 90}}`,
+		},
+		{
+			name: "liveness probe budget too small",
+			container: `{"name": "app",
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "initialDelaySeconds": 30}}`,
+			expectedMessage: "container 'app': startup budget is 60s (liveness probe initialDelaySeconds 30 + " +
+				"periodSeconds 10 × failureThreshold 3), less than max_startup_seconds (120s)",
+		},
+		{
+			name:      "no probe restarting the container",
+			container: `{"name": "app", "readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := Settings{MaxStartupSeconds: 120}
+			deployment := []byte(`{"spec": {"template": {"spec": {"containers": [` + test.container + `]}}}}`)
+
+			assertValidateDeployment(t, "Deployment", deployment, settings, test.expectedMessage)
+		})
+	}
+}
+
+func TestValidateDetectionTime(t *testing.T) {
+	settings := Settings{
+		LivenessProbe:  ProbeConfig{MaxDetectionSeconds: 90},
+		ReadinessProbe: ProbeConfig{MaxDetectionSeconds: 30},
+	}
+
+	accepted := []byte(`{"spec": {"template": {"spec": {"containers": [{"name": "app",
+		"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "periodSeconds": 20, "timeoutSeconds": 5},
+		"readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}, "periodSeconds": 5, "timeoutSeconds": 2}
+	}]}}}}`)
+	if err := validateDeployment("Deployment", accepted, settings); err != nil {
+		t.Errorf("Expected deployment to be accepted, got: %v", err)
+	}
+
+	rejected := []byte(`{"spec": {"template": {"spec": {"containers": [{"name": "app",
+		"livenessProbe": {"httpGet": {"path": "/healthz", "port": 8080}, "periodSeconds": 30, "timeoutSeconds": 5},
+		"readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}
+	}]}}}}`)
+	err := validateDeployment("Deployment", rejected, settings)
+	if err == nil {
+		t.Fatal("Expected deployment to be rejected")
+	}
+
+	expected := "container 'app': liveness probe worst-case detection time is 95s " +
+		"(periodSeconds 30 × failureThreshold 3 + timeoutSeconds 5), exceeds max_detection_seconds (90s); " +
+		"readiness probe worst-case detection time is 31s " +
+		"(periodSeconds 10 × failureThreshold 3 + timeoutSeconds 1), exceeds max_detection_seconds (30s)"
+	if err.Error() != expected {
+		t.Errorf("Expected message %q, got %q", expected, err.Error())
+	}
+}
+
+func TestValidateTimeoutToPeriodRatio(t *testing.T) {
+	tests := []struct {
+		name            string
+		probe           string
+		expectedMessage string
+	}{
+		{
+			name:  "ratio within limit",
+			probe: `{"tcpSocket": {"port": 8080}, "periodSeconds": 10, "timeoutSeconds": 5}`,
+		},
+		{
+			name:  "defaults within limit",
+			probe: `{"tcpSocket": {"port": 8080}}`,
+		},
+		{
+			name:  "timeout equal to period",
+			probe: `{"tcpSocket": {"port": 8080}, "periodSeconds": 10, "timeoutSeconds": 10}`,
+			expectedMessage: "container 'app': liveness probe timeoutSeconds/periodSeconds ratio is 1.00 (10s/10s), " +
+				"exceeds max_timeout_to_period_ratio (0.50)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := Settings{LivenessProbe: ProbeConfig{MaxTimeoutToPeriodRatio: 0.5}}
+			deployment := []byte(`{"spec": {"template": {"spec": {"containers": [
+				{"name": "app", "livenessProbe": ` + test.probe + `}
+			]}}}}`)
+
+			assertValidateDeployment(t, "Deployment", deployment, settings, test.expectedMessage)
+		})
+	}
+}
+
+func TestValidateHandlerTimingOverrides(t *testing.T) {
+	rawSettings := `{
+		"liveness_probe": {
+			"max_timeout_seconds": 2,
+			"min_failure_threshold": 3,
+			"exec": {"max_timeout_seconds": 10},
+			"tcp": {"max_timeout_seconds": 1}
+		},
+		"readiness_probe": {"required": false}
+	}`
+	settings := Settings{}
+	if err := json.Unmarshal([]byte(rawSettings), &settings); err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+
+	tests := []struct {
+		name            string
+		probe           string
+		expectedMessage string
+	}{
+		{
+			name:  "exec probe uses exec bounds",
+			probe: `{"exec": {"command": ["pg_isready"]}, "timeoutSeconds": 8}`,
+		},
+		{
+			name:            "exec probe inherits probe-level bounds",
+			probe:           `{"exec": {"command": ["pg_isready"]}, "timeoutSeconds": 8, "failureThreshold": 1}`,
+			expectedMessage: "container 'app': liveness probe failureThreshold (1) is less than minimum required (3)",
+		},
+		{
+			name:            "tcp probe uses tcp bounds",
+			probe:           `{"tcpSocket": {"port": 8080}, "timeoutSeconds": 2}`,
+			expectedMessage: "container 'app': liveness probe timeoutSeconds (2s) exceeds maximum allowed (1s)",
+		},
+		{
+			name:            "http probe uses probe-level bounds",
+			probe:           `{"httpGet": {"path": "/healthz", "port": 8080}, "timeoutSeconds": 8}`,
+			expectedMessage: "container 'app': liveness probe timeoutSeconds (8s) exceeds maximum allowed (2s)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deployment := []byte(`{"spec": {"template": {"spec": {"containers": [
+				{"name": "app", "livenessProbe": ` + test.probe + `}
+			]}}}}`)
+
+			assertValidateDeployment(t, "Deployment", deployment, settings, test.expectedMessage)
+		})
+	}
+}
+
+func TestValidateContainerOverrides(t *testing.T) {
+	rawSettings := `{
+		"liveness_probe": {"required": true},
+		"readiness_probe": {"required": true},
+		"container_overrides": [
+			{"name": "istio-proxy", "exempt": true},
+			{"name": "regex:^(fluent-bit|cloud-sql-proxy)$", "liveness_probe": {"required": false}},
+			{"name": "*-proxy", "readiness_probe": {"required": true, "allowed_handlers": ["httpGet"]}}
+		]
+	}`
+	settings := Settings{}
+	if err := json.Unmarshal([]byte(rawSettings), &settings); err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+
+	tests := []struct {
+		name            string
+		container       string
+		expectedMessage string
+	}{
+		{
+			name:            "container without override",
+			container:       `{"name": "app", "readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}}`,
+			expectedMessage: "container 'app': missing liveness probe",
+		},
+		{
+			name:      "exempt container",
+			container: `{"name": "istio-proxy"}`,
+		},
+		{
+			name:      "override relaxing liveness requirement",
+			container: `{"name": "fluent-bit", "readinessProbe": {"httpGet": {"path": "/ready", "port": 2020}}}`,
+		},
+		{
+			name:            "override keeps base configuration of other probes",
+			container:       `{"name": "cloud-sql-proxy"}`,
+			expectedMessage: "container 'cloud-sql-proxy': missing readiness probe",
+		},
+		{
+			name: "first matching override applies",
+			container: `{"name": "envoy-proxy",
+				"livenessProbe": {"httpGet": {"path": "/healthz", "port": 9901}},
+				"readinessProbe": {"tcpSocket": {"port": 9901}}}`,
+			expectedMessage: "container 'envoy-proxy': readiness probe handler 'tcpSocket' is not allowed " +
+				"(allowed: httpGet)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deployment := []byte(`{"spec": {"template": {"spec": {"containers": [` + test.container + `]}}}}`)
+
+			assertValidateDeployment(t, "Deployment", deployment, settings, test.expectedMessage)
+		})
+	}
+}
+
+func TestValidateImageRules(t *testing.T) {
+	rawSettings := `{
+		"readiness_probe": {"required": true},
+		"image_rules": [
+			{"image": "*/openjdk*", "ignore_tag": true, "startup_probe": {"required": true}},
+			{"image": "*spring*", "startup_probe": {"required": true}},
+			{"image": "registry.example.com:5000/vendor/*", "ignore_tag": true, "exempt": true},
+			{"image": "*/openjdk-batch", "ignore_tag": true, "readiness_probe": {"required": false}}
+		],
+		"container_overrides": [
+			{"name": "legacy-*", "startup_probe": {"required": false}}
+		]
+	}`
+	settings := Settings{}
+	if err := json.Unmarshal([]byte(rawSettings), &settings); err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+
+	tests := []struct {
+		name            string
+		container       string
+		expectedMessage string
+	}{
+		{
+			name: "jvm image requires startup probe",
+			container: `{"name": "app", "image": "docker.io/library/openjdk:17",
+				"readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}}`,
+			expectedMessage: "container 'app': missing startup probe",
+		},
+		{
+			name: "jvm image with startup probe",
+			container: `{"name": "app", "image": "docker.io/library/openjdk@sha256:0123abcd",
+				"readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}},
+				"startupProbe": {"httpGet": {"path": "/healthz", "port": 8080}}}`,
+		},
+		{
+			name:            "image matched with tag",
+			container:       `{"name": "app", "image": "ghcr.io/acme/spring-app:1.2.3"}`,
+			expectedMessage: "container 'app': missing readiness probe; missing startup probe",
+		},
+		{
+			name:      "vendor image exempt",
+			container: `{"name": "vendor", "image": "registry.example.com:5000/vendor/appliance:2.0"}`,
+		},
+		{
+			name:            "later rules merge over earlier ones",
+			container:       `{"name": "batch", "image": "quay.io/acme/openjdk-batch:21"}`,
+			expectedMessage: "container 'batch': missing startup probe",
+		},
+		{
+			name: "container override applies after image rules",
+			container: `{"name": "legacy-app", "image": "docker.io/library/openjdk:8",
+				"readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deployment := []byte(`{"spec": {"template": {"spec": {"containers": [` + test.container + `]}}}}`)
+
+			assertValidateDeployment(t, "Deployment", deployment, settings, test.expectedMessage)
+		})
+	}
+}
+
+func TestValidateNamespaces(t *testing.T) {
+	settings := `{
+		"liveness_probe": {"required": true},
+		"excluded_namespaces": ["kube-system", "legacy-*"],
+		"namespace_overlays": [
+			{"namespaces": ["monitoring"], "liveness_probe": {"required": false}}
+		]
+	}`
+	deployment := `{
+		"apiVersion": "apps/v1",
+		"kind": "Deployment",
+		"metadata": {"name": "app", "namespace": "legacy-billing"},
+		"spec": {"template": {"spec": {"containers": [
+			{"name": "app", "readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}}
+		]}}}
+	}`
+
+	tests := []struct {
+		name        string
+		namespace   string
+		settings    string
+		shouldAllow bool
+	}{
+		{name: "enforced namespace", namespace: "default", settings: settings, shouldAllow: false},
+		{name: "excluded namespace", namespace: "kube-system", settings: settings, shouldAllow: true},
+		{name: "excluded namespace pattern", namespace: "legacy-payments", settings: settings, shouldAllow: true},
+		{name: "namespace from object metadata", namespace: "", settings: settings, shouldAllow: true},
+		{name: "namespace overlay", namespace: "monitoring", settings: settings, shouldAllow: true},
+		{
+			name:      "namespace overlay takes precedence over the profile",
+			namespace: "monitoring",
+			settings: `{"default_profile": "web", "profiles": {"web": {"liveness_probe": {"required": true}}},
+				"namespace_overlays": [{"namespaces": ["monitoring"], "liveness_probe": {"required": false}}]}`,
+			shouldAllow: true,
+		},
+		{
+			name:      "profile applies outside the namespace overlay",
+			namespace: "default",
+			settings: `{"default_profile": "web", "profiles": {"web": {"liveness_probe": {"required": true}}},
+				"namespace_overlays": [{"namespaces": ["monitoring"], "liveness_probe": {"required": false}}]}`,
+			shouldAllow: false,
+		},
+		{
+			name:        "namespace not included",
+			namespace:   "default",
+			settings:    `{"liveness_probe": {"required": true}, "included_namespaces": ["team-*"]}`,
+			shouldAllow: true,
+		},
+		{
+			name:        "namespace included",
+			namespace:   "team-web",
+			settings:    `{"liveness_probe": {"required": true}, "included_namespaces": ["team-*"]}`,
+			shouldAllow: false,
+		},
+		{
+			name:      "exclusion takes precedence over inclusion",
+			namespace: "team-legacy",
+			settings: `{"liveness_probe": {"required": true},
+				"included_namespaces": ["team-*"], "excluded_namespaces": ["*-legacy"]}`,
+			shouldAllow: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := kubewarden_protocol.ValidationRequest{
+				Request: kubewarden_protocol.KubernetesAdmissionRequest{
+					Operation: "CREATE",
+					Namespace: test.namespace,
+					Object:    json.RawMessage(deployment),
+				},
+				Settings: json.RawMessage(test.settings),
+			}
+
+			response := validateRequest(t, request)
+			if response.Accepted != test.shouldAllow {
+				t.Errorf("Expected validation to return %v, got %v. Message: %v",
+					test.shouldAllow, response.Accepted, response.Message)
+			}
+		})
+	}
+}
+
+func TestValidateExemptRequesters(t *testing.T) {
+	settings := `{
+		"liveness_probe": {"required": true},
+		"exempt_users": ["oncall-*@example.com"],
+		"exempt_groups": ["break-glass"],
+		"exempt_service_accounts": ["argocd:*", "system:serviceaccount:flux-system:helm-*"]
+	}`
+	deployment := `{
+		"apiVersion": "apps/v1",
+		"kind": "Deployment",
+		"metadata": {"name": "app", "namespace": "default"},
+		"spec": {"template": {"spec": {"containers": [
+			{"name": "app", "readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}}
+		]}}}
+	}`
+
+	tests := []struct {
+		name        string
+		userInfo    kubewarden_protocol.UserInfo
+		shouldAllow bool
+	}{
+		{
+			name:        "regular user",
+			userInfo:    kubewarden_protocol.UserInfo{Username: "dev@example.com", Groups: []string{"developers"}},
+			shouldAllow: false,
+		},
+		{
+			name:        "exempt user",
+			userInfo:    kubewarden_protocol.UserInfo{Username: "oncall-alice@example.com"},
+			shouldAllow: true,
+		},
+		{
+			name: "exempt group",
+			userInfo: kubewarden_protocol.UserInfo{
+				Username: "bob@example.com",
+				Groups:   []string{"developers", "break-glass"},
+			},
+			shouldAllow: true,
+		},
+		{
+			name:        "exempt service account",
+			userInfo:    kubewarden_protocol.UserInfo{Username: "system:serviceaccount:argocd:argocd-application-controller"},
+			shouldAllow: true,
+		},
+		{
+			name:        "exempt service account by full username",
+			userInfo:    kubewarden_protocol.UserInfo{Username: "system:serviceaccount:flux-system:helm-controller"},
+			shouldAllow: true,
+		},
+		{
+			name:        "service account in another namespace",
+			userInfo:    kubewarden_protocol.UserInfo{Username: "system:serviceaccount:flux-system:kustomize-controller"},
+			shouldAllow: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := kubewarden_protocol.ValidationRequest{
+				Request: kubewarden_protocol.KubernetesAdmissionRequest{
+					Operation: "CREATE",
+					Uid:       "705ab4f5-6393-11e8-b7cc-42010a800002",
+					UserInfo:  test.userInfo,
+					Object:    json.RawMessage(deployment),
+				},
+				Settings: json.RawMessage(settings),
+			}
+
+			response := validateRequest(t, request)
+			if response.Accepted != test.shouldAllow {
+				t.Errorf("Expected validation to return %v, got %v. Message: %v",
+					test.shouldAllow, response.Accepted, response.Message)
+			}
+		})
+	}
+}
+
+func TestValidateProfiles(t *testing.T) {
+	settings := Settings{
+		ReadinessProbe: ProbeConfig{Required: true},
+		ProfileLabel:   "workload-class",
+		DefaultProfile: "web",
+		Profiles: map[string]ProbeOverrides{
+			"web":    {LivenessProbe: &ProbeConfig{Required: true}},
+			"worker": {ReadinessProbe: &ProbeConfig{Required: false}, StartupProbe: &ProbeConfig{Required: true}},
+			"batch":  {ReadinessProbe: &ProbeConfig{Required: false}},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		labels          string
+		templateLabels  string
+		settings        *Settings
+		expectedMessage string
+	}{
+		{
+			name:            "default profile",
+			expectedMessage: "profile 'web': container 'app': missing liveness probe; missing readiness probe",
+		},
+		{
+			name:            "profile selected by workload label",
+			labels:          `{"workload-class": "worker"}`,
+			expectedMessage: "profile 'worker': container 'app': missing startup probe",
+		},
+		{
+			name:           "profile selected by pod template label",
+			templateLabels: `{"workload-class": "batch"}`,
+		},
+		{
+			name:            "workload label takes precedence",
+			labels:          `{"workload-class": "worker"}`,
+			templateLabels:  `{"workload-class": "batch"}`,
+			expectedMessage: "profile 'worker': container 'app': missing startup probe",
+		},
+		{
+			name:            "unknown profile falls back to the default profile",
+			labels:          `{"workload-class": "cron"}`,
+			expectedMessage: "profile 'web': container 'app': missing liveness probe; missing readiness probe",
+		},
+		{
+			name:   "unknown profile without default profile",
+			labels: `{"workload-class": "cron"}`,
+			settings: &Settings{
+				ReadinessProbe: ProbeConfig{Required: true},
+				ProfileLabel:   "workload-class",
+				Profiles:       map[string]ProbeOverrides{"web": {}},
+			},
+			expectedMessage: "container 'app': missing readiness probe",
+		},
+		{
+			name:            "no profile",
+			labels:          `{"workload-class": "worker"}`,
+			settings:        &Settings{ReadinessProbe: ProbeConfig{Required: true}},
+			expectedMessage: "container 'app': missing readiness probe",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			labels := test.labels
+			if labels == "" {
+				labels = "{}"
+			}
+			templateLabels := test.templateLabels
+			if templateLabels == "" {
+				templateLabels = "{}"
+			}
+			testSettings := settings
+			if test.settings != nil {
+				testSettings = *test.settings
+			}
+			deployment := []byte(`{
+				"apiVersion": "apps/v1",
+				"kind": "Deployment",
+				"metadata": {"name": "app", "labels": ` + labels + `},
+				"spec": {"template": {"metadata": {"labels": ` + templateLabels + `}, "spec": {"containers": [
+					{"name": "app", "image": "nginx"}
+				]}}}
+			}`)
+
+			assertValidateDeployment(t, "Deployment", deployment, testSettings, test.expectedMessage)
+		})
+	}
+}
+
+func TestValidateRequiredWhenReplicasAtLeast(t *testing.T) {
+	settings := Settings{
+		ReadinessProbe: ProbeConfig{Required: true, RequiredWhenReplicasAtLeast: 2},
+	}
+
+	tests := []struct {
+		name            string
+		kind            string
+		spec            string
+		expectedMessage string
+	}{
+		{
+			name: "scaled to zero",
+			kind: "Deployment",
+			spec: `"replicas": 0, "template": {"spec": {"containers": [{"name": "app"}]}}`,
+		},
+		{
+			name: "single replica",
+			kind: "StatefulSet",
+			spec: `"replicas": 1, "template": {"spec": {"containers": [{"name": "app"}]}}`,
+		},
+		{
+			name:            "enough replicas",
+			kind:            "Deployment",
+			spec:            `"replicas": 2, "template": {"spec": {"containers": [{"name": "app"}]}}`,
+			expectedMessage: "container 'app': missing readiness probe",
+		},
+		{
+			name:            "replicas managed by an autoscaler",
+			kind:            "Deployment",
+			spec:            `"template": {"spec": {"containers": [{"name": "app"}]}}`,
+			expectedMessage: "container 'app': missing readiness probe",
+		},
+		{
+			name:            "kind without replicas",
+			kind:            "DaemonSet",
+			spec:            `"template": {"spec": {"containers": [{"name": "app"}]}}`,
+			expectedMessage: "container 'app': missing readiness probe",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			object := []byte(`{"kind": "` + test.kind + `", "spec": {` + test.spec + `}}`)
+
+			assertValidateDeployment(t, test.kind, object, settings, test.expectedMessage)
+		})
+	}
+}
+
+func TestValidateGrandfatherOnUpdate(t *testing.T) {
+	settings := `{
+		"liveness_probe": {"required": true},
+		"readiness_probe": {"required": true, "max_period_seconds": 10},
+		"grandfather_on_update": true
+	}`
+	legacy := `{
+		"apiVersion": "apps/v1",
+		"kind": "Deployment",
+		"metadata": {"name": "app"},
+		"spec": {"template": {"spec": {"containers": [
+			{"name": "app", "image": "app:1.0", "readinessProbe": {"tcpSocket": {"port": 8080}, "periodSeconds": 30}}
+		]}}}
+	}`
+
+	tests := []struct {
+		name            string
+		operation       string
+		object          string
+		settings        string
+		expectedMessage string
+	}{
+		{
+			name:      "image bump of a legacy deployment",
+			operation: "UPDATE",
+			object:    strings.Replace(legacy, "app:1.0", "app:1.1", 1),
+			settings:  settings,
+		},
+		{
+			name:            "worse probe on update",
+			operation:       "UPDATE",
+			object:          strings.Replace(legacy, `"periodSeconds": 30`, `"periodSeconds": 60`, 1),
+			settings:        settings,
+			expectedMessage: "container 'app': readiness probe periodSeconds (60s) exceeds maximum allowed (10s)",
+		},
+		{
+			name:      "new container on update",
+			operation: "UPDATE",
+			object: strings.Replace(legacy, `]}}}`,
+				`, {"name": "sidecar", "readinessProbe": {"tcpSocket": {"port": 9090}}}]}}}`, 1),
+			settings:        settings,
+			expectedMessage: "container 'sidecar': missing liveness probe",
+		},
+		{
+			name:      "create is not grandfathered",
+			operation: "CREATE",
+			object:    legacy,
+			settings:  settings,
+			expectedMessage: "container 'app': missing liveness probe; " +
+				"readiness probe periodSeconds (30s) exceeds maximum allowed (10s)",
+		},
+		{
+			name:            "grandfathering disabled",
+			operation:       "UPDATE",
+			object:          legacy,
+			settings:        `{"liveness_probe": {"required": true}}`,
+			expectedMessage: "container 'app': missing liveness probe",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := kubewarden_protocol.ValidationRequest{
+				Request: kubewarden_protocol.KubernetesAdmissionRequest{
+					Operation: test.operation,
+					Object:    json.RawMessage(test.object),
+					OldObject: json.RawMessage(legacy),
+				},
+				Settings: json.RawMessage(test.settings),
+			}
+
+			response := validateRequest(t, request)
+			if test.expectedMessage == "" {
+				if !response.Accepted {
+					t.Errorf("Expected validation to accept, got message: %v", *response.Message)
+				}
+				return
+			}
+			if response.Accepted {
+				t.Fatalf("Expected validation to reject with %q", test.expectedMessage)
+			}
+			if *response.Message != test.expectedMessage {
+				t.Errorf("Expected message %q, got %q", test.expectedMessage, *response.Message)
+			}
+		})
+	}
+}
+
+func TestValidateOperationsSubresourcesAndDryRun(t *testing.T) {
+	containers := make([]string, 0, 40)
+	for i := 0; i < 40; i++ {
+		containers = append(containers, fmt.Sprintf(`{"name": "container-with-a-long-name-%d"}`, i))
+	}
+	deployment := `{"kind": "Deployment", "spec": {"template": {"spec": {"containers": [` +
+		strings.Join(containers, ", ") + `]}}}}`
+
+	tests := []struct {
+		name        string
+		operation   string
+		subResource string
+		dryRun      bool
+		settings    string
+		shouldAllow bool
+		fullMessage bool
+	}{
+		{name: "create", operation: "CREATE", settings: `{}`, shouldAllow: false},
+		{name: "update", operation: "UPDATE", settings: `{}`, shouldAllow: false},
+		{name: "delete is not enforced", operation: "DELETE", settings: `{}`, shouldAllow: true},
+		{name: "connect is not enforced", operation: "CONNECT", settings: `{}`, shouldAllow: true},
+		{name: "missing operation is validated", operation: "", settings: `{}`, shouldAllow: false},
+		{name: "scale subresource", operation: "UPDATE", subResource: "scale", settings: `{}`, shouldAllow: true},
+		{name: "status subresource", operation: "UPDATE", subResource: "status", settings: `{}`, shouldAllow: true},
+		{
+			name:        "update not enforced",
+			operation:   "UPDATE",
+			settings:    `{"enforced_operations": ["CREATE"]}`,
+			shouldAllow: true,
+		},
+		{name: "dry run", operation: "CREATE", dryRun: true, settings: `{}`, shouldAllow: false},
+		{
+			name:        "dry run with full message",
+			operation:   "CREATE",
+			dryRun:      true,
+			settings:    `{"dry_run_full_message": true}`,
+			shouldAllow: false,
+			fullMessage: true,
+		},
+		{
+			name:        "full message only for dry runs",
+			operation:   "CREATE",
+			settings:    `{"dry_run_full_message": true}`,
+			shouldAllow: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := kubewarden_protocol.ValidationRequest{
+				Request: kubewarden_protocol.KubernetesAdmissionRequest{
+					Operation:   test.operation,
+					SubResource: test.subResource,
+					DryRun:      test.dryRun,
+					Object:      json.RawMessage(deployment),
+				},
+				Settings: json.RawMessage(test.settings),
+			}
+
+			response := validateRequest(t, request)
+			if response.Accepted != test.shouldAllow {
+				t.Fatalf("Expected validation to return %v, got %v. Message: %v",
+					test.shouldAllow, response.Accepted, response.Message)
+			}
+			if test.shouldAllow {
+				return
+			}
+
+			capped := strings.HasSuffix(*response.Message, "more violations)")
+			if test.fullMessage == capped {
+				t.Errorf("Expected full message %v, got %q", test.fullMessage, *response.Message)
+			}
+			if test.fullMessage && strings.Count(*response.Message, "missing readiness probe") != len(containers) {
+				t.Errorf("Expected every violation in the message, got %q", *response.Message)
+			}
+		})
+	}
+}
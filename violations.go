@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxViolationsMessageLength caps the length of the rejection message built from the violations。
+const maxViolationsMessageLength = 1024
+
+// moreViolationsFormat reports how many violations a capped message left out。
+const moreViolationsFormat = " (and %d more violations)"
+
+// truncationMarker ends a violation cut to fit the capped message。
+const truncationMarker = "..."
+
+// violation describes a single probe misconfiguration found in a container。
+type violation struct {
+	// container is the name of the container the violation belongs to。
+	container string
+	// probe is the probe type ("liveness", "readiness" or "startup") the violation belongs to。
+	probe string
+	// message describes the violation。
+	message string
+	// missing reports that the violation is a required probe the container does not define。
+	missing bool
+}
+
+// violations collects every probe violation found in a pod spec。
+type violations struct {
+	// profile is the name of the settings profile the pod spec was validated against, if any。
+	profile string
+	items   []violation
+}
+
+// add records a violation for the given container and probe type。
+func (v *violations) add(container, probe, format string, args ...interface{}) {
+	v.items = append(v.items, violation{
+		container: container,
+		probe:     probe,
+		message:   fmt.Sprintf(format, args...),
+	})
+}
+
+// addMissing records that the container does not define a required probe。
+func (v *violations) addMissing(container, probe string) {
+	v.items = append(v.items, violation{
+		container: container,
+		probe:     probe,
+		message:   fmt.Sprintf("missing %s probe", probe),
+		missing:   true,
+	})
+}
+
+// missingProbes returns the probe types of the missing required probes, keyed by container name。
+func (v *violations) missingProbes() map[string][]string {
+	missing := map[string][]string{}
+	for _, item := range v.items {
+		if item.missing {
+			missing[item.container] = append(missing[item.container], item.probe)
+		}
+	}
+	return missing
+}
+
+// empty reports whether no violation has been collected。
+func (v *violations) empty() bool {
+	return len(v.items) == 0
+}
+
+// withoutProbes returns the violations that do not belong to any of the given probe types。
+func (v *violations) withoutProbes(probeTypes []string) *violations {
+	kept := &violations{profile: v.profile}
+	for _, item := range v.items {
+		if !containsString(probeTypes, item.probe) {
+			kept.items = append(kept.items, item)
+		}
+	}
+	return kept
+}
+
+// split separates the violations already present in previous, compared by container, probe and
+// message, from the newly introduced ones。
+func (v *violations) split(previous *violations) (*violations, *violations) {
+	introduced := &violations{profile: v.profile}
+	preexisting := &violations{profile: v.profile}
+	for _, item := range v.items {
+		if containsViolation(previous.items, item) {
+			preexisting.items = append(preexisting.items, item)
+		} else {
+			introduced.items = append(introduced.items, item)
+		}
+	}
+	return introduced, preexisting
+}
+
+// containsViolation reports whether the violation is part of the list。
+func containsViolation(items []violation, item violation) bool {
+	for _, candidate := range items {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
+
+// Error returns the violations grouped by container, capped to maxViolationsMessageLength。
+func (v *violations) Error() string {
+	return v.message(maxViolationsMessageLength)
+}
+
+// message returns the violations grouped by container, in the order the containers were
+// first seen and prefixed with the profile name, if any。A maxLength greater than zero caps the
+// message, suffix included, and reports how many violations were left out。A first violation too
+// long to fit on its own is cut。
+func (v *violations) message(maxLength int) string {
+	containers := []string{}
+	grouped := map[string][]string{}
+	for _, item := range v.items {
+		if _, found := grouped[item.container]; !found {
+			containers = append(containers, item.container)
+		}
+		grouped[item.container] = append(grouped[item.container], item.message)
+	}
+
+	var builder strings.Builder
+	if v.profile != "" {
+		fmt.Fprintf(&builder, "profile '%s': ", v.profile)
+	}
+	written := 0
+	for _, container := range containers {
+		for i, message := range grouped[container] {
+			part := "; " + message
+			if i == 0 {
+				part = fmt.Sprintf("container '%s': %s", container, message)
+				if written > 0 {
+					part = ". " + part
+				}
+			}
+
+			// Keep room for the suffix reporting the violations left after this one。
+			suffix := ""
+			if remaining := len(v.items) - written - 1; remaining > 0 {
+				suffix = fmt.Sprintf(moreViolationsFormat, remaining)
+			}
+			if maxLength > 0 && builder.Len()+len(part)+len(suffix) > maxLength {
+				if written > 0 {
+					fmt.Fprintf(&builder, moreViolationsFormat, len(v.items)-written)
+					return builder.String()
+				}
+				builder.WriteString(truncate(part, maxLength-builder.Len()-len(suffix)))
+				builder.WriteString(suffix)
+				return builder.String()
+			}
+			builder.WriteString(part)
+			written++
+		}
+	}
+
+	return builder.String()
+}
+
+// truncate cuts the text to maxLength bytes, truncation marker included, on a rune boundary。
+func truncate(text string, maxLength int) string {
+	if len(text) <= maxLength {
+		return text
+	}
+	end := maxLength - len(truncationMarker)
+	if end <= 0 {
+		return ""
+	}
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return text[:end] + truncationMarker
+}
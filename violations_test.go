@@ -0,0 +1,122 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestViolationsMessageGroupsByContainer(t *testing.T) {
+	found := &violations{}
+	found.add("app", "liveness", "missing liveness probe")
+	found.add("sidecar", "readiness", "missing readiness probe")
+	found.add("app", "readiness", "readiness probe timeoutSeconds (10s) exceeds maximum allowed (5s)")
+
+	expected := "container 'app': missing liveness probe; " +
+		"readiness probe timeoutSeconds (10s) exceeds maximum allowed (5s). " +
+		"container 'sidecar': missing readiness probe"
+	if message := found.message(0); message != expected {
+		t.Errorf("Expected message %q, got %q", expected, message)
+	}
+}
+
+func TestViolationsMessageIsCapped(t *testing.T) {
+	found := &violations{}
+	for i := 0; i < 100; i++ {
+		found.add("container-with-a-long-name", "liveness", "missing liveness probe")
+	}
+
+	message := found.Error()
+	if len(message) > maxViolationsMessageLength {
+		t.Errorf("Expected message to be capped, got %d characters", len(message))
+	}
+	if !strings.HasSuffix(message, "more violations)") {
+		t.Errorf("Expected message to report the omitted violations, got %q", message)
+	}
+
+	if full := found.message(0); strings.Count(full, "missing liveness probe") != 100 {
+		t.Errorf("Expected uncapped message to list every violation, got %q", full)
+	}
+}
+
+func TestViolationsMessageCutsLongViolation(t *testing.T) {
+	command := strings.Repeat("× ", 3000)
+
+	tests := []struct {
+		name           string
+		count          int
+		expectedSuffix string
+	}{
+		{name: "single violation", count: 1, expectedSuffix: truncationMarker},
+		{name: "several violations", count: 3, expectedSuffix: "... (and 2 more violations)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			found := &violations{profile: "worker"}
+			for i := 0; i < test.count; i++ {
+				found.add("app", "liveness", "liveness probe command '%s' is not allowed", command)
+			}
+
+			message := found.Error()
+			if len(message) > maxViolationsMessageLength {
+				t.Errorf("Expected message to be capped, got %d characters", len(message))
+			}
+			if !strings.HasSuffix(message, test.expectedSuffix) {
+				t.Errorf("Expected message to end with %q, got %q", test.expectedSuffix, message)
+			}
+			if !utf8.ValidString(message) {
+				t.Errorf("Expected message to be valid UTF-8, got %q", message)
+			}
+		})
+	}
+}
+
+func TestViolationsEmpty(t *testing.T) {
+	found := &violations{}
+	if !found.empty() {
+		t.Error("Expected new collector to be empty")
+	}
+
+	found.add("app", "startup", "missing startup probe")
+	if found.empty() {
+		t.Error("Expected collector with a violation not to be empty")
+	}
+}
+
+func TestViolationsMessageNamesProfile(t *testing.T) {
+	found := &violations{profile: "worker"}
+	found.add("app", "liveness", "missing liveness probe")
+	found.add("sidecar", "startup", "missing startup probe")
+
+	expected := "profile 'worker': container 'app': missing liveness probe. container 'sidecar': missing startup probe"
+	if message := found.message(0); message != expected {
+		t.Errorf("Expected message %q, got %q", expected, message)
+	}
+
+	if message := found.withoutProbes([]string{"liveness"}).message(0); !strings.HasPrefix(message, "profile 'worker': ") {
+		t.Errorf("Expected filtered violations to keep the profile, got %q", message)
+	}
+}
+
+func TestViolationsSplit(t *testing.T) {
+	previous := &violations{}
+	previous.add("app", "liveness", "missing liveness probe")
+	previous.add("app", "readiness", "readiness probe periodSeconds (30s) exceeds maximum allowed (10s)")
+
+	found := &violations{}
+	found.add("app", "liveness", "missing liveness probe")
+	found.add("app", "readiness", "readiness probe periodSeconds (60s) exceeds maximum allowed (10s)")
+	found.add("worker", "liveness", "missing liveness probe")
+
+	introduced, preexisting := found.split(previous)
+
+	expected := "container 'app': readiness probe periodSeconds (60s) exceeds maximum allowed (10s). " +
+		"container 'worker': missing liveness probe"
+	if message := introduced.message(0); message != expected {
+		t.Errorf("Expected introduced violations %q, got %q", expected, message)
+	}
+	if message := preexisting.message(0); message != "container 'app': missing liveness probe" {
+		t.Errorf("Expected pre-existing violations to hold the unchanged violation, got %q", message)
+	}
+}